@@ -9,18 +9,30 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/audit"
 	"github.com/drone/go-task/task/cloner"
 	"github.com/drone/go-task/task/common"
 	download "github.com/drone/go-task/task/downloader"
 	"github.com/drone/go-task/task/drivers/cgi"
+	httpdriver "github.com/drone/go-task/task/drivers/http"
 	"github.com/drone/go-task/task/expression"
+	"github.com/drone/go-task/task/forward"
+	"github.com/drone/go-task/task/logstream"
+	"github.com/drone/go-task/task/masker"
+	"github.com/drone/go-task/task/metrics"
+	"github.com/drone/go-task/task/middleware"
 	"github.com/drone/go-task/task/packaged"
+	"github.com/drone/go-task/task/tracing"
 )
 
 var (
@@ -36,6 +48,32 @@ var (
 	// displays the help / usage if true
 	help = flag.Bool("help", false, "")
 
+	// address of the optional prometheus metrics endpoint
+	metricsAddr = flag.String("metrics-addr", "", "")
+
+	// path of the file to which trace spans are exported
+	traceFile = flag.String("trace-file", "", "")
+
+	// path of the append-only audit log file
+	auditFile = flag.String("audit-file", "", "")
+
+	// run cgi tasks in a throwaway working directory as
+	// the sandbox user
+	sandbox = flag.Bool("sandbox", false, "")
+
+	// isolate cgi tasks using linux namespaces and landlock,
+	// which implies the sandbox
+	isolate = flag.Bool("isolate", false, "")
+
+	// uid and gid of sandboxed cgi tasks, which default to
+	// the runner user, or nobody if the runner is root
+	sandboxUID = flag.Uint("sandbox-uid", 0, "")
+	sandboxGID = flag.Uint("sandbox-gid", 0, "")
+
+	// register the sample exec driver, which runs arbitrary
+	// commands on the host and is disabled by default
+	execDriver = flag.Bool("exec-driver", false, "")
+
 	// resolve mode flags
 	resolveExpr = flag.String("resolve", "", "")
 	secretsJSON = flag.String("secrets", "", "")
@@ -43,6 +81,19 @@ var (
 
 func main() {
 
+	// run the init process of an isolated cgi task, if
+	// the program was started as the init process.
+	cgi.Init()
+
+	// exit only after run returns, so that deferred
+	// cleanup, such as flushing trace spans and closing
+	// the audit log, is not skipped on failure.
+	os.Exit(run())
+}
+
+// run runs the program and returns the exit code.
+func run() int {
+
 	// parse the input parameters
 	flag.BoolVar(help, "h", false, "")
 	flag.BoolVar(verbose, "v", false, "")
@@ -51,13 +102,19 @@ func main() {
 
 	if *help {
 		flag.Usage()
-		os.Exit(0)
+		return 0
+	}
+
+	// handle routes mode
+	if flag.Arg(0) == "routes" {
+		handleRoutes(flag.Args()[1:])
+		return 0
 	}
 
 	// handle resolve mode
 	if *resolveExpr != "" {
 		handleResolve(*resolveExpr, *secretsJSON)
-		return
+		return 0
 	}
 
 	// set the default log level
@@ -84,15 +141,116 @@ func main() {
 	// parse the task file
 	data, err := os.ReadFile(*path)
 	if err != nil {
-		log.Fatalln(err)
+		log.Println(err)
+		return 1
 	}
 
 	// unmarshal the task file into a request
 	req := new(task.Request)
 	if err := json.Unmarshal(data, req); err != nil {
-		log.Fatalln(err)
+		log.Println(err)
+		return 1
+	}
+
+	// serve the prometheus metrics when the metrics
+	// address is provided.
+	if *metricsAddr != "" {
+		go serveMetrics(*metricsAddr)
+	}
+
+	// export trace spans to the OTLP endpoint when it is
+	// configured in the environment, or to the file when
+	// the trace file is provided for debugging.
+	switch {
+	case *traceFile != "":
+		file, err := os.Create(*traceFile)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer file.Close()
+		shutdown, err := tracing.SetupWriter(file)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer shutdown(context.Background())
+	case tracing.Configured():
+		shutdown, err := tracing.Setup(context.Background())
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer shutdown(context.Background())
+	}
+
+	// append an audit entry for every task execution
+	// when the audit file is provided.
+	var sink audit.Sink
+	if *auditFile != "" {
+		fileSink, err := audit.NewFileSink(*auditFile)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer fileSink.Close()
+		sink = fileSink
+	}
+
+	// create the task router
+	router := newRouter(sink)
+
+	// handle the request, forwarding the task to a remote
+	// runner node if the task includes forwarding instructions.
+	res := forward.Handler(router).Handle(context.Background(), req)
+	// if the response is an error, print the structured
+	// error as json, with the underlying error masked using
+	// the task secrets, and exit with failure.
+	if err := res.Error(); err != nil {
+		slog.Debug("task failed", "error", err)
+		json.NewEncoder(os.Stdout).Encode(map[string]any{
+			"error": failureJSON{
+				Failure: task.AsFailure(err),
+				Detail:  maskError(err, req.Secrets),
+			},
+		})
+		return 1
+	}
+
+	if *pretty {
+		// write the task details to stdout
+		fmt.Fprintf(os.Stdout, "id:   %s", req.Task.ID)
+		fmt.Fprintln(os.Stdout, "")
+		fmt.Fprintf(os.Stdout, "type: %s", req.Task.Type)
+		fmt.Fprintln(os.Stdout, "")
+		fmt.Fprintln(os.Stdout, "")
+
+		// decode the response body into a temporary
+		// data structure.
+		var temp any
+		json.Unmarshal(res.Body(), &temp)
+
+		// re-encode the response body as json with
+		// indentation.
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(temp)
+		return 0
 	}
 
+	// write the logs to stdout
+	os.Stdout.Write(res.Body())
+	return 0
+}
+
+// nobody is the uid and gid of sandboxed tasks when the
+// runner is root.
+const nobody = 65534
+
+// newRouter returns the task router with the built-in
+// handlers, drivers and middleware. Task executions are
+// written to the audit sink, if not nil.
+func newRouter(sink audit.Sink) *task.Router {
 	cache, err := os.UserCacheDir()
 	if err != nil {
 		log.Fatalln(err)
@@ -113,59 +271,144 @@ func main() {
 
 		// top-level directory where the downloading should happen
 		filepath.Join(cache, "download"),
+
+		// record download metrics, trace spans and audit
+		// entries.
+		downloadObserver{},
 	)
 	packageLoader := packaged.New(filepath.Join(cache, "default"))
 
+	// run cgi tasks in a throwaway working directory as an
+	// unprivileged user when the sandbox is enabled, and
+	// isolated from the host when isolation is enabled.
+	var opts cgi.Options
+	if *sandbox || *isolate {
+		opts.Sandbox = &cgi.Sandbox{
+			Isolate: *isolate,
+			UID:     uint32(*sandboxUID),
+			GID:     uint32(*sandboxGID),
+		}
+		// sandboxed tasks do not run as root.
+		if opts.Sandbox.UID == 0 && os.Getuid() == 0 {
+			opts.Sandbox.UID = nobody
+		}
+		if opts.Sandbox.GID == 0 && os.Getuid() == 0 {
+			opts.Sandbox.GID = nobody
+		}
+	}
+
+	// create the cgi driver
+	cgiDriver := cgi.NewWithOptions(
+		// use the default downloader which
+		// caches tasks at ~/.cache/harness/task
+		downloader,
+		packageLoader,
+		opts,
+	)
+
 	// create the task router
 	router := task.NewRouter()
-	router.RegisterFunc("sample/exec", execHandler) // sample bult-in handler
-	router.RegisterFunc("sample/file", fileHandler) // sample bult-in handler
+
+	// record task metrics.
+	router.Use(metrics.Handler)
+
+	// audit task executions, including executions that
+	// panic, which are recovered by the next middleware.
+	if sink != nil {
+		router.Use(audit.Handler(sink))
+	}
+
+	// recover from handler panics, so that a panic does
+	// not terminate the runner.
+	router.Use(middleware.Recover)
+
+	// stream task logs to the log service when the
+	// task includes logging instructions.
+	router.Use(logstream.Handler)
+
+	router.Register("sample/exec", execRoute) // sample bult-in handler
+	router.Register("sample/file", fileRoute) // sample bult-in handler
+	router.RegisterDriver("cgi", cgiDriver)
+	router.RegisterDriver("http", httpdriver.New(nil))
+	if *execDriver {
+		router.RegisterDriver("exec", execRoute) // sample bult-in driver
+	}
 	router.NotFound(
 		// default to cgi handler when no built-in
-		// task handler is found.
-		cgi.New(
-			// use the default downloader which
-			// caches tasks at ~/.cache/harness/task
-			downloader,
-			packageLoader,
-		),
+		// task handler or driver is found.
+		cgiDriver,
 	)
+	return router
+}
 
-	// handle the request
-	res := router.Handle(context.Background(), req)
-	if err := res.Error(); err != nil {
-		log.Fatalln(err)
-	}
+// failureJSON is the structured error printed when the
+// task fails, including the masked underlying error.
+type failureJSON struct {
+	*task.Failure
+	Detail string `json:"detail,omitempty"`
+}
 
-	// if the response is an error, print the error
-	// message and exit with failure.
-	if err := res.Error(); err != nil {
-		log.Fatalln(err)
+// maskError returns the error message with the secrets
+// masked.
+func maskError(err error, secrets []*common.Secret) string {
+	var buf strings.Builder
+	io.WriteString(masker.New(&buf, masker.Slice(secrets)), err.Error())
+	return buf.String()
+}
+
+// serveMetrics serves the prometheus metrics at /metrics.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Server())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("metrics server failed", "error", err)
 	}
+}
 
-	if *pretty {
-		// write the task details to stdout
-		fmt.Fprintf(os.Stdout, "id:   %s", req.Task.ID)
-		fmt.Fprintln(os.Stdout, "")
-		fmt.Fprintf(os.Stdout, "type: %s", req.Task.Type)
-		fmt.Fprintln(os.Stdout, "")
-		fmt.Fprintln(os.Stdout, "")
+// handleRoutes prints the catalog of the routes, drivers
+// and middleware registered to the router.
+func handleRoutes(args []string) {
+	flags := flag.NewFlagSet("routes", flag.ExitOnError)
+	format := flags.String("format", "table", "")
+	flags.Usage = usage
+	flags.Parse(args)
 
-		// decode the response body into a temporary
-		// data structure.
-		var temp any
-		json.Unmarshal(res.Body(), &temp)
+	catalog := newRouter(nil).Catalog()
 
-		// re-encode the response body as json with
-		// indentation.
+	switch *format {
+	case "json":
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		enc.Encode(temp)
-		return
+		enc.Encode(catalog)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "KIND\tPATTERN\tVERSION\tDESCRIPTION")
+		for _, info := range catalog.Routes {
+			printRoute(w, "route", info)
+		}
+		for _, info := range catalog.Drivers {
+			printRoute(w, "driver", info)
+		}
+		if catalog.NotFound != nil {
+			printRoute(w, "notfound", catalog.NotFound)
+		}
+		w.Flush()
+		fmt.Fprintln(os.Stdout, "")
+		fmt.Fprintf(os.Stdout, "middleware: %s", strings.Join(catalog.Middleware, ", "))
+		fmt.Fprintln(os.Stdout, "")
+	default:
+		log.Fatalf("Unknown format: %s", *format)
 	}
+}
 
-	// write the logs to stdout
-	os.Stdout.Write(res.Body())
+// printRoute writes the route info as a table row.
+func printRoute(w io.Writer, kind string, info *task.RouteInfo) {
+	var version, description string
+	if info.Metadata != nil {
+		version = info.Version
+		description = info.Description
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", kind, info.Pattern, version, description)
 }
 
 func handleResolve(inputJson, secretsJSON string) {
@@ -198,18 +441,32 @@ func handleResolve(inputJson, secretsJSON string) {
 
 var usage = func() {
 	println(`Usage: go-task [OPTION]... [PATH]
+  or:  go-task routes [--format table|json]
 
       --path           path to the task file
       --pretty         pretty print the task output
+      --metrics-addr   serve prometheus metrics at /metrics on the address
+      --trace-file     export trace spans to the file as json, for debugging;
+                       otherwise spans are exported to OTEL_EXPORTER_OTLP_ENDPOINT
+      --audit-file     append an audit entry for every task to the file
+      --sandbox        run cgi tasks in a throwaway working directory as the sandbox user
+      --isolate        isolate cgi tasks using linux namespaces and landlock, implies --sandbox
+      --sandbox-uid    uid of sandboxed cgi tasks, defaults to nobody for a root runner
+      --sandbox-gid    gid of sandboxed cgi tasks, defaults to nobody for a root runner
+      --exec-driver    register the sample exec driver, which runs host commands
   -v, --verbose        execute the task with verbose output
   -h, --help           display this help and exit
 
+  Routes Mode:
+      --format         output format of the catalog, table or json
+
   Expression Resolver Mode:
       --resolve        expression string to resolve
       --secrets        JSON array of secrets [{"id":"key","value":"val"}]
 
 Examples:
   go-task path/to/task.json
+  go-task routes --format json
   go-task --resolve "Hello \${{secrets.name}}" --secrets '[{"id":"name","value":"World"}]'
 `)
 }
@@ -0,0 +1,59 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+
+	"github.com/drone/go-task/task/audit"
+	download "github.com/drone/go-task/task/downloader"
+	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/metrics"
+	"github.com/drone/go-task/task/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// downloadObserver records metrics, trace spans and audit
+// entries for artifact downloads.
+type downloadObserver struct{}
+
+// Start starts the download span.
+func (downloadObserver) Start(ctx context.Context, d *download.Download) context.Context {
+	ctx, _ = tracing.Start(ctx, "download."+d.Kind, tracing.TaskType.String(d.TaskType))
+	return ctx
+}
+
+// Finish records the download metrics and audit entry, and
+// ends the download span.
+func (downloadObserver) Finish(ctx context.Context, d *download.Download, err error) {
+	metrics.Download(d.Kind, d.CacheHit)
+	if err != nil {
+		metrics.DownloadError(d.Kind)
+	}
+
+	switch {
+	case d.Repository != nil:
+		audit.Record(ctx, &audit.Artifact{
+			Repository: d.Repository.Clone,
+			Ref:        d.Repository.Ref,
+			Sha:        d.Repository.Sha,
+			Download:   d.Repository.Download,
+		})
+	case d.Executable != nil && err == nil:
+		hashErr := audit.RecordFile(ctx, &audit.Artifact{
+			Name:    d.Executable.Name,
+			Version: d.Executable.Version,
+			URLs:    d.URLs,
+		}, d.Path)
+		if hashErr != nil {
+			logger.FromContext(ctx).WithError(hashErr).Warn("cannot hash executable for audit log")
+		}
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(tracing.CacheHit.Bool(d.CacheHit))
+	tracing.End(span, err)
+}
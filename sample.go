@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"strings"
@@ -42,6 +43,70 @@ type (
 	}
 )
 
+// Validate validates the exec task input.
+func (in *execInput) Validate() error {
+	if len(in.Script) == 0 {
+		return &task.DecodeError{Field: "script", Err: errors.New("required")}
+	}
+	return nil
+}
+
+// Validate validates the file task input.
+func (in *fileInput) Validate() error {
+	if in.Path == "" {
+		return &task.DecodeError{Field: "path", Err: errors.New("required")}
+	}
+	return nil
+}
+
+// sample handlers with metadata, which is listed in the
+// task catalog.
+var (
+	execRoute = task.DescribeFunc(task.Typed(execHandler), task.Metadata{
+		Description: "Sample handler that executes a shell script.",
+		Version:     "1.0.0",
+		Input: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"shell": {"type": "string"},
+				"script": {"type": "array", "items": {"type": "string"}},
+				"envs": {"type": "array", "items": {"type": "string"}}
+			},
+			"required": ["script"]
+		}`),
+		Output: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"pid": {"type": "integer"},
+				"exited": {"type": "boolean"},
+				"exit_code": {"type": "integer"},
+				"user_time": {"type": "integer"},
+				"sys_time": {"type": "integer"},
+				"out": {"type": "array", "items": {"type": "string"}}
+			}
+		}`),
+	})
+
+	fileRoute = task.DescribeFunc(task.Typed(fileHandler), task.Metadata{
+		Description: "Sample handler that reads a secret from a file.",
+		Version:     "1.0.0",
+		Input: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string"}
+			},
+			"required": ["path"]
+		}`),
+		Output: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string"},
+				"value": {"type": "string"}
+			}
+		}`),
+	})
+)
+
 // Sample handler that exposes os/exec as a task. This is a
 // sample only and is not meant for production use.
 //
@@ -57,14 +122,7 @@ type (
 //	        }
 //	    }
 //	}
-func execHandler(ctx context.Context, req *task.Request) task.Response {
-	var conf = new(execInput)
-
-	// decode the task configuration
-	if err := json.Unmarshal(req.Task.Data, &conf); err != nil {
-		return task.Error(err)
-	}
-
+func execHandler(ctx context.Context, req *task.Request, conf *execInput) (*execOutput, error) {
 	// create a buffer for stdout / stderr, wrapped
 	// in the secret masker
 	buf := new(bytes.Buffer)
@@ -82,7 +140,7 @@ func execHandler(ctx context.Context, req *task.Request) task.Response {
 
 	// execute the command
 	if err := cmd.Run(); err != nil {
-		return task.Error(err)
+		return nil, err
 	}
 
 	// collect the output
@@ -97,7 +155,7 @@ func execHandler(ctx context.Context, req *task.Request) task.Response {
 		Output: strings.Split(buf.String(), "\n"),
 	}
 
-	return task.Respond(out)
+	return out, nil
 }
 
 // Sample handler that reads a file as a task. This is a
@@ -114,25 +172,15 @@ func execHandler(ctx context.Context, req *task.Request) task.Response {
 //	        }
 //	    }
 //	}
-func fileHandler(ctx context.Context, req *task.Request) task.Response {
-	conf := new(fileInput)
-
-	// decode the task configuration.
-	err := json.Unmarshal(req.Task.Data, conf)
-	if err != nil {
-		return task.Error(err)
-	}
-
+func fileHandler(ctx context.Context, req *task.Request, conf *fileInput) (*common.Secret, error) {
 	// read the secret from the file.
 	contents, err := os.ReadFile(conf.Path)
 	if err != nil {
-		return task.Error(err)
+		return nil, err
 	}
 
 	// write the secret to the response.
-	return task.Respond(
-		&common.Secret{
-			Value: string(contents),
-		},
-	)
+	return &common.Secret{
+		Value: string(contents),
+	}, nil
 }
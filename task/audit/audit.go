@@ -0,0 +1,200 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package audit provides an audit log of task executions.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+)
+
+// now returns the current time, and can be replaced
+// in unit tests.
+var now = time.Now
+
+// outcomes of the task execution.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry is an audit record of a task execution.
+type Entry struct {
+	RequestID string      `json:"request_id,omitempty"`
+	TaskID    string      `json:"task_id"`
+	Type      string      `json:"type"`
+	Driver    string      `json:"driver,omitempty"`
+	Account   string      `json:"account,omitempty"`
+	Artifacts []*Artifact `json:"artifacts,omitempty"`
+	Outcome   string      `json:"outcome"`
+	Code      task.Code   `json:"code,omitempty"`
+
+	// Started and Finished provide the time the task handler
+	// was started and finished. The router resolves secret
+	// sub-tasks before the handler is started, so the resolve
+	// phase is excluded.
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	// Secrets provides the names, but not the values, of
+	// the secrets available to the task execution.
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Prev provides the hash of the previous entry, and
+	// Hash provides the hash of the entry, including the
+	// hash of the previous entry. Set by the sink.
+	Prev string `json:"prev,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// Artifact describes the source of the task artifact.
+type Artifact struct {
+	// Repository provides the repository clone url, ref
+	// and commit sha, or the repository archive url.
+	Repository string `json:"repository,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+	Sha        string `json:"sha,omitempty"`
+	Download   string `json:"download,omitempty"`
+
+	// Name and Version provide the executable name and
+	// version, URLs provides the executable download urls,
+	// which are empty for a prepackaged executable, Path
+	// provides the local executable path, and SHA256
+	// provides the executable hash.
+	Name    string   `json:"name,omitempty"`
+	Version string   `json:"version,omitempty"`
+	URLs    []string `json:"urls,omitempty"`
+	Path    string   `json:"path,omitempty"`
+	SHA256  string   `json:"sha256,omitempty"`
+}
+
+// Sink writes audit entries.
+type Sink interface {
+	Write(context.Context, *Entry) error
+}
+
+// Handler returns a middleware that writes an audit entry
+// to the sink for every task execution.
+func Handler(sink Sink) func(task.Handler) task.Handler {
+	return func(next task.Handler) task.Handler {
+		return task.HandlerFunc(func(ctx context.Context, req *task.Request) task.Response {
+			entry := &Entry{
+				RequestID: req.ID,
+				TaskID:    req.Task.ID,
+				Type:      req.Task.Type,
+				Driver:    req.Task.Driver,
+				Account:   req.Account,
+				Started:   now().UTC(),
+			}
+
+			rec := new(recorder)
+			res := next.Handle(context.WithValue(ctx, recorderKey{}, rec), req)
+
+			// secrets are resolved by the router, and are
+			// available once the task is handled.
+			entry.Finished = now().UTC()
+			entry.Secrets = secretNames(req)
+			entry.Artifacts = rec.artifacts()
+			entry.Outcome = OutcomeSuccess
+			if res != nil {
+				if failure := task.AsFailure(res.Error()); failure != nil {
+					entry.Outcome = OutcomeFailure
+					entry.Code = failure.Code
+				}
+			}
+			if err := sink.Write(ctx, entry); err != nil {
+				logger.FromContext(ctx).WithError(err).Error("cannot write audit entry")
+			}
+			return res
+		})
+	}
+}
+
+// Audited reports whether the task execution is audited.
+func Audited(ctx context.Context) bool {
+	_, ok := ctx.Value(recorderKey{}).(*recorder)
+	return ok
+}
+
+// Record records the artifact of the task execution. It
+// is a no-op if the task execution is not audited.
+func Record(ctx context.Context, artifact *Artifact) {
+	if rec, ok := ctx.Value(recorderKey{}).(*recorder); ok {
+		rec.add(artifact)
+	}
+}
+
+// RecordFile records the artifact with the local path and
+// hash of the artifact file. The artifact is recorded
+// without the hash if the file cannot be read, and the
+// error is returned. It is a no-op if the task execution is
+// not audited.
+func RecordFile(ctx context.Context, artifact *Artifact, path string) error {
+	if !Audited(ctx) {
+		return nil
+	}
+	artifact.Path = path
+	sum, err := hashFile(path)
+	artifact.SHA256 = sum
+	Record(ctx, artifact)
+	return err
+}
+
+// hashFile returns the sha256 hash of the file contents.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// recorderKey is the context key of the recorder.
+type recorderKey struct{}
+
+// recorder records the artifacts of a task execution.
+type recorder struct {
+	mu   sync.Mutex
+	list []*Artifact
+}
+
+func (r *recorder) add(artifact *Artifact) {
+	r.mu.Lock()
+	r.list = append(r.list, artifact)
+	r.mu.Unlock()
+}
+
+func (r *recorder) artifacts() []*Artifact {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list
+}
+
+// secretNames returns the names of the request secrets,
+// excluding the secrets derived from expressions.
+func secretNames(req *task.Request) []string {
+	var names []string
+	for _, secret := range req.Secrets {
+		if strings.HasPrefix(secret.ID, "__derivedSecret_") {
+			continue
+		}
+		names = append(names, secret.ID)
+	}
+	return names
+}
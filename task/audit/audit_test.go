@@ -0,0 +1,113 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+)
+
+// memorySink is a sink that stores entries in memory.
+type memorySink struct {
+	entries []*Entry
+}
+
+func (s *memorySink) Write(_ context.Context, entry *Entry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	sink := new(memorySink)
+	router := task.NewRouter()
+	router.Use(Handler(sink))
+	router.RegisterFunc("ping", func(ctx context.Context, req *task.Request) task.Response {
+		if !Audited(ctx) {
+			t.Errorf("Expect task execution audited")
+		}
+		Record(ctx, &Artifact{Repository: "https://github.com/octocat/hello-world.git", Sha: "762941318ee16e59dabbacb1b4049eec22f0d303"})
+		return task.Respond("pong")
+	})
+	router.RegisterFunc("fail", func(context.Context, *task.Request) task.Response {
+		return task.Error(task.Fail(task.CodeExec, task.PhaseExec, "boom", nil))
+	})
+
+	router.Handle(context.Background(), &task.Request{
+		ID:      "1",
+		Account: "acme",
+		Task:    &task.Task{ID: "a", Type: "ping"},
+		Secrets: []*common.Secret{
+			{ID: "token", Value: "correct-horse-battery-staple"},
+			{ID: "__derivedSecret_0", Value: "derived"},
+		},
+	})
+	router.Handle(context.Background(), &task.Request{
+		ID:   "2",
+		Task: &task.Task{ID: "b", Type: "fail"},
+	})
+
+	if got, want := len(sink.entries), 2; got != want {
+		t.Fatalf("Want %d audit entries, got %d", want, got)
+	}
+
+	want := &Entry{
+		RequestID: "1",
+		TaskID:    "a",
+		Type:      "ping",
+		Account:   "acme",
+		Artifacts: []*Artifact{
+			{Repository: "https://github.com/octocat/hello-world.git", Sha: "762941318ee16e59dabbacb1b4049eec22f0d303"},
+		},
+		Started:  now().UTC(),
+		Finished: now().UTC(),
+		Outcome:  OutcomeSuccess,
+		Secrets:  []string{"token"},
+	}
+	if got := sink.entries[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Want audit entry %+v, got %+v", want, got)
+	}
+
+	if got := sink.entries[1]; got.Outcome != OutcomeFailure || got.Code != task.CodeExec {
+		t.Errorf("Want failure with code %s, got %s %s", task.CodeExec, got.Outcome, got.Code)
+	}
+}
+
+func TestHandler_SinkError(t *testing.T) {
+	sink := sinkFunc(func(context.Context, *Entry) error {
+		return errors.New("disk full")
+	})
+	handler := Handler(sink)(task.HandlerFunc(func(context.Context, *task.Request) task.Response {
+		return task.Respond("pong")
+	}))
+	res := handler.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "ping"}})
+	if err := res.Error(); err != nil {
+		t.Errorf("Expect sink error does not fail the task, got %s", err)
+	}
+}
+
+func TestRecord_NotAudited(t *testing.T) {
+	ctx := context.Background()
+	if Audited(ctx) {
+		t.Errorf("Expect task execution not audited")
+	}
+	// no-op when the task execution is not audited.
+	Record(ctx, &Artifact{})
+}
+
+// sinkFunc adapts a function to the Sink interface.
+type sinkFunc func(context.Context, *Entry) error
+
+func (fn sinkFunc) Write(ctx context.Context, entry *Entry) error {
+	return fn(ctx, entry)
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/drone/go-task/task/logger"
+)
+
+// FileSink is an append-only sink that writes entries to
+// a file as JSON lines. Each entry includes the hash of the
+// previous entry, so that modified, removed or re-ordered
+// entries are detected by Verify.
+//
+// The file is locked while an entry is appended, and the
+// entries appended by other processes since the last write
+// are read to continue the hash chain, so that multiple
+// runners can share the file.
+type FileSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	last   string
+	offset int64
+}
+
+// NewFileSink returns a FileSink that appends entries to
+// the file at path. The hash chain is continued if the file
+// already contains entries.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends the entry to the file.
+func (s *FileSink) Write(ctx context.Context, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := lock(s.file); err != nil {
+		return fmt.Errorf("cannot lock audit log: %w", err)
+	}
+	defer unlock(s.file)
+
+	// read the entries appended since the last write, which
+	// may have been appended by another process.
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	if size := info.Size(); size > s.offset {
+		last, n, err := lastHash(io.NewSectionReader(s.file, s.offset, size-s.offset))
+		if err != nil {
+			return fmt.Errorf("cannot read audit log: %w", err)
+		}
+		// remove a torn line left by a writer that failed
+		// or crashed mid-write, so that the chain continues
+		// from the last complete entry.
+		if s.offset+n < size {
+			logger.FromContext(ctx).Warn("removing torn entry from audit log")
+			if err := s.file.Truncate(s.offset + n); err != nil {
+				return fmt.Errorf("cannot repair audit log: %w", err)
+			}
+		}
+		if last != "" {
+			s.last = last
+		}
+		s.offset += n
+	}
+
+	entry.Prev = s.last
+	hash, err := hashEntry(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := s.file.Write(line); err != nil {
+		// remove the partially written line.
+		s.file.Truncate(s.offset)
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.last = hash
+	s.offset += int64(len(line))
+	return nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Verify reads the entries and verifies the hash chain. An
+// error is returned for the first entry that was modified,
+// or that does not follow the previous entry.
+func Verify(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	var prev string
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := new(Entry)
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return fmt.Errorf("audit entry %d: %w", line, err)
+		}
+		if entry.Prev != prev {
+			return fmt.Errorf("audit entry %d: does not follow the previous entry", line)
+		}
+		hash, err := hashEntry(entry)
+		if err != nil {
+			return fmt.Errorf("audit entry %d: %w", line, err)
+		}
+		if hash != entry.Hash {
+			return fmt.Errorf("audit entry %d: hash mismatch", line)
+		}
+		prev = entry.Hash
+	}
+	return scanner.Err()
+}
+
+// hashEntry returns the hash of the entry, excluding the
+// entry hash, which includes the hash of the previous entry.
+func hashEntry(entry *Entry) (string, error) {
+	copy := *entry
+	copy.Hash = ""
+	b, err := json.Marshal(&copy)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastHash returns the hash of the last entry, and the
+// length of the complete lines read. A trailing line that
+// is not terminated by a newline is a torn write, and is
+// excluded.
+func lastHash(r io.Reader) (string, int64, error) {
+	reader := bufio.NewReader(r)
+
+	var last string
+	var n int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return last, n, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		n += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry := new(Entry)
+		if err := json.Unmarshal(line, entry); err != nil {
+			return "", 0, err
+		}
+		last = entry.Hash
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &Entry{TaskID: "a", Type: "ping", Outcome: OutcomeSuccess}
+	second := &Entry{TaskID: "b", Type: "ping", Outcome: OutcomeSuccess}
+	sink.Write(context.Background(), first)
+	sink.Write(context.Background(), second)
+	sink.Close()
+
+	if first.Prev != "" {
+		t.Errorf("Want first entry without previous hash, got %s", first.Prev)
+	}
+	if second.Prev != first.Hash {
+		t.Errorf("Want previous hash %s, got %s", first.Hash, second.Prev)
+	}
+
+	// re-opening the file continues the hash chain.
+	sink, err = NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third := &Entry{TaskID: "c", Type: "ping", Outcome: OutcomeFailure}
+	sink.Write(context.Background(), third)
+	sink.Close()
+
+	if third.Prev != second.Hash {
+		t.Errorf("Want previous hash %s, got %s", second.Hash, third.Prev)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Count(string(data), "\n"), 3; got != want {
+		t.Errorf("Want %d lines, got %d", want, got)
+	}
+	if err := Verify(bytes.NewReader(data)); err != nil {
+		t.Errorf("Expect valid hash chain, got %s", err)
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		sink.Write(context.Background(), &Entry{TaskID: id, Outcome: OutcomeSuccess})
+	}
+	sink.Close()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// modified entry
+	modified := strings.Replace(string(data), `"task_id":"b"`, `"task_id":"x"`, 1)
+	if err := Verify(strings.NewReader(modified)); err == nil || !strings.Contains(err.Error(), "entry 2") {
+		t.Errorf("Want hash mismatch for entry 2, got %v", err)
+	}
+
+	// removed entry
+	removed := lines[0] + lines[2]
+	if err := Verify(strings.NewReader(removed)); err == nil || !strings.Contains(err.Error(), "entry 2") {
+		t.Errorf("Want broken chain for entry 2, got %v", err)
+	}
+}
+
+func TestFileSink_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// two sinks, as opened by concurrent runners, append
+	// to the same file.
+	a, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	first := &Entry{TaskID: "a", Outcome: OutcomeSuccess}
+	second := &Entry{TaskID: "b", Outcome: OutcomeSuccess}
+	third := &Entry{TaskID: "c", Outcome: OutcomeSuccess}
+	a.Write(context.Background(), first)
+	b.Write(context.Background(), second)
+	a.Write(context.Background(), third)
+
+	if second.Prev != first.Hash || third.Prev != second.Hash {
+		t.Errorf("Want hash chain continued across sinks")
+	}
+	data, _ := os.ReadFile(path)
+	if err := Verify(bytes.NewReader(data)); err != nil {
+		t.Errorf("Expect valid hash chain, got %s", err)
+	}
+}
+
+func TestVerify_BlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		sink.Write(context.Background(), &Entry{TaskID: id, Outcome: OutcomeSuccess})
+	}
+	sink.Close()
+
+	data, _ := os.ReadFile(path)
+	if err := Verify(strings.NewReader(strings.ReplaceAll(string(data), "\n", "\n\n"))); err != nil {
+		t.Errorf("Want blank lines skipped, got %s", err)
+	}
+}
+
+func TestFileSink_TornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	first := &Entry{TaskID: "a", Outcome: OutcomeSuccess}
+	if err := sink.Write(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+
+	// a runner that crashed mid-write leaves a torn line.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"task_id":"torn","outc`)
+	file.Close()
+
+	second := &Entry{TaskID: "b", Outcome: OutcomeSuccess}
+	if err := sink.Write(context.Background(), second); err != nil {
+		t.Fatal(err)
+	}
+	if second.Prev != first.Hash {
+		t.Errorf("Want hash chain continued from the last complete entry")
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "torn") {
+		t.Errorf("Want torn line removed")
+	}
+	if err := Verify(bytes.NewReader(data)); err != nil {
+		t.Errorf("Expect valid hash chain, got %s", err)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/drone/go-task/task"
 	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/tracing"
 )
 
 type Builder struct {
@@ -22,7 +24,15 @@ func New(taskYmlPath string) *Builder {
 }
 
 // Build parses the task.yml file and generates the executable binary for a task
-func (b *Builder) Build(ctx context.Context) (string, error) {
+func (b *Builder) Build(ctx context.Context) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "builder.build")
+	defer func() {
+		if err != nil {
+			err = task.Fail(task.CodeBuild, task.PhaseBuild, "failed to build task", err)
+		}
+		tracing.End(span, err)
+	}()
+
 	log := logger.FromContext(ctx)
 	out, err := ParseFile(b.TaskYmlPath)
 	if err != nil {
@@ -80,7 +90,7 @@ func (b *Builder) installAptDeps(ctx context.Context, deps []AptDep) error {
 	if len(deps) > 0 {
 		log.Info("apt-get update")
 
-		cmd := b.cmdRunner("sudo", "apt-get", "update")
+		cmd := b.cmdRunner(ctx, "sudo", "apt-get", "update")
 		err = cmd.Run()
 		if err != nil {
 			return err
@@ -90,7 +100,7 @@ func (b *Builder) installAptDeps(ctx context.Context, deps []AptDep) error {
 	for _, dep := range deps {
 		log.Info("apt-get install", slog.String("package", dep.Name))
 
-		cmd := b.cmdRunner("sudo", "apt-get", "install", dep.Name)
+		cmd := b.cmdRunner(ctx, "sudo", "apt-get", "install", dep.Name)
 		if err = cmd.Run(); err != nil {
 			// TODO: perhaps errors can be logged as warnings instead of returning here,
 			// but we can evaluate this in the future.
@@ -103,8 +113,9 @@ func (b *Builder) installAptDeps(ctx context.Context, deps []AptDep) error {
 
 // cmdRunner returns a new exec.Cmd with the given name and arguments
 // It populates the working directory as the directory of the task.yml file.
-func (b *Builder) cmdRunner(name string, arg ...string) *exec.Cmd {
-	cmd := exec.Command(name, arg...)
+// The command is killed when the context is cancelled.
+func (b *Builder) cmdRunner(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, arg...)
 	cmd.Dir = filepath.Dir(b.TaskYmlPath)
 	return cmd
 }
@@ -118,7 +129,7 @@ func (b *Builder) buildGoModule(
 	log.Info("go build", slog.String("module", module))
 
 	// build the code
-	cmd := b.cmdRunner("go", "build", "-o", binName, module)
+	cmd := b.cmdRunner(ctx, "go", "build", "-o", binName, module)
 	if err := cmd.Run(); err != nil {
 		return err
 	}
@@ -130,7 +141,7 @@ func (b *Builder) installBrewDeps(ctx context.Context, deps []BrewDep) error {
 	for _, item := range deps {
 		log.Info("brew install", slog.String("package", item.Name))
 
-		cmd := b.cmdRunner("brew", "install", item.Name)
+		cmd := b.cmdRunner(ctx, "brew", "install", item.Name)
 		if err := cmd.Run(); err != nil {
 			// TODO: perhaps errors can be logged as a warning instead of returning here,
 			// but we can evaluate this in the future.
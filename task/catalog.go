@@ -0,0 +1,143 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+// Metadata provides optional handler metadata.
+type Metadata struct {
+	// Description provides a short description of the task.
+	Description string `json:"description,omitempty"`
+
+	// Version provides the handler version.
+	Version string `json:"version,omitempty"`
+
+	// Input provides the JSON schema of the task data.
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Output provides the JSON schema of the task output.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// A Describer is a Handler that provides metadata.
+type Describer interface {
+	Describe() *Metadata
+}
+
+// Describe returns a Handler that provides the metadata.
+func Describe(handler Handler, meta Metadata) Handler {
+	return &described{handler: handler, meta: meta}
+}
+
+// DescribeFunc returns a Handler for the HandlerFunc that
+// provides the metadata.
+func DescribeFunc(handler HandlerFunc, meta Metadata) Handler {
+	return Describe(handler, meta)
+}
+
+// described is a Handler with metadata.
+type described struct {
+	handler Handler
+	meta    Metadata
+}
+
+func (d *described) Handle(ctx context.Context, req *Request) Response {
+	return d.handler.Handle(ctx, req)
+}
+
+func (d *described) Describe() *Metadata {
+	return &d.meta
+}
+
+// Decode decodes the task output using the wrapped handler,
+// if the wrapped handler implements Decoder.
+func (d *described) Decode(res Response) ([]byte, error) {
+	if decoder, ok := d.handler.(Decoder); ok {
+		return decoder.Decode(res)
+	}
+	return res.Body(), nil
+}
+
+// Catalog describes the routes, drivers and middleware
+// registered to the router.
+type Catalog struct {
+	Routes     []*RouteInfo `json:"routes"`
+	Drivers    []*RouteInfo `json:"drivers"`
+	NotFound   *RouteInfo   `json:"not_found,omitempty"`
+	Middleware []string     `json:"middleware"`
+}
+
+// RouteInfo describes a registered route or driver.
+type RouteInfo struct {
+	// Pattern provides the task type pattern for routes,
+	// or the driver name for drivers.
+	Pattern string `json:"pattern"`
+
+	// Middleware provides the names of the route group
+	// middleware, if any.
+	Middleware []string `json:"middleware,omitempty"`
+
+	// Metadata provides the optional handler metadata.
+	*Metadata `json:",omitempty"`
+}
+
+// Catalog returns the catalog of the routes, drivers and
+// middleware registered to the router.
+func (h *Router) Catalog() *Catalog {
+	catalog := &Catalog{
+		Routes:     []*RouteInfo{},
+		Drivers:    []*RouteInfo{},
+		Middleware: middlewareNames(h.middleware),
+	}
+	for _, rt := range h.routes.all() {
+		info := describe(rt.pattern, rt.handler)
+		if rt.group != nil {
+			info.Middleware = middlewareNames(rt.group.stack())
+		}
+		catalog.Routes = append(catalog.Routes, info)
+	}
+	for name, driver := range h.drivers {
+		catalog.Drivers = append(catalog.Drivers, describe(name, driver))
+	}
+	sort.Slice(catalog.Drivers, func(i, j int) bool {
+		return catalog.Drivers[i].Pattern < catalog.Drivers[j].Pattern
+	})
+	if h.notfound != nil {
+		catalog.NotFound = describe("*", h.notfound)
+	}
+	return catalog
+}
+
+// describe returns the route info for the handler.
+func describe(pattern string, handler Handler) *RouteInfo {
+	info := &RouteInfo{Pattern: pattern}
+	if describer, ok := handler.(Describer); ok {
+		info.Metadata = describer.Describe()
+	}
+	return info
+}
+
+// closureSuffix matches the suffix of anonymous functions.
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// middlewareNames returns the function names of the
+// middleware, for example middleware.Retry.
+func middlewareNames(middleware []func(Handler) Handler) []string {
+	names := []string{}
+	for _, fn := range middleware {
+		name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+		name = closureSuffix.ReplaceAllString(name, "")
+		names = append(names, path.Base(name))
+	}
+	return names
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testMiddleware(next Handler) Handler {
+	return next
+}
+
+func TestRouter_Catalog(t *testing.T) {
+	noop := func(context.Context, *Request) Response { return nil }
+
+	router := NewRouter()
+	router.Use(testMiddleware)
+	// anonymous middleware is named after the enclosing function.
+	router.Use(func(next Handler) Handler { return next })
+	router.Register("secret/file", DescribeFunc(noop, Metadata{
+		Description: "reads a secret from a file",
+		Version:     "1.0.0",
+		Input:       json.RawMessage(`{"type":"object"}`),
+	}))
+	router.RegisterFunc("secret/*", noop)
+	router.Route("k8s", func(g *Group) {
+		g.Use(testMiddleware)
+		g.RegisterFunc("apply", noop)
+	})
+	router.RegisterDriverFunc("exec", noop)
+	router.RegisterDriver("cgi", DescribeFunc(noop, Metadata{Description: "cgi driver"}))
+	router.NotFoundFunc(noop)
+
+	got := router.Catalog()
+	want := &Catalog{
+		Routes: []*RouteInfo{
+			{Pattern: "k8s/apply", Middleware: []string{"task.testMiddleware"}},
+			{Pattern: "secret/*"},
+			{Pattern: "secret/file", Metadata: &Metadata{
+				Description: "reads a secret from a file",
+				Version:     "1.0.0",
+				Input:       json.RawMessage(`{"type":"object"}`),
+			}},
+		},
+		Drivers: []*RouteInfo{
+			{Pattern: "cgi", Metadata: &Metadata{Description: "cgi driver"}},
+			{Pattern: "exec"},
+		},
+		NotFound:   &RouteInfo{Pattern: "*"},
+		Middleware: []string{"task.testMiddleware", "task.TestRouter_Catalog"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		a, _ := json.Marshal(got)
+		b, _ := json.Marshal(want)
+		t.Errorf("Want catalog %s, got %s", b, a)
+	}
+}
+
+func TestDescribe_Decode(t *testing.T) {
+	h := Describe(&testDriver{}, Metadata{})
+	if _, ok := h.(Decoder); !ok {
+		t.Errorf("Expect described handler implements Decoder")
+	}
+	body, err := h.(Decoder).Decode(Respond(&CGITaskResponse{
+		StatusCode: 200,
+		Body:       "aGVsbG8=",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "hello"; got != want {
+		t.Errorf("Want decoded body %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dispatcher provides bounded concurrency and a
+// work queue in front of a task handler.
+package dispatcher
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+)
+
+// ErrQueueFull is returned when the task cannot be
+// executed or queued because the queue is full.
+var ErrQueueFull = errors.New("task queue is full")
+
+// Config configures the dispatcher.
+type Config struct {
+	// Concurrency provides the maximum number of tasks
+	// executed concurrently. Zero means no limit.
+	Concurrency int
+
+	// Limits provides the maximum number of tasks executed
+	// concurrently by task type. Zero means no limit.
+	Limits map[string]int
+
+	// QueueSize provides the maximum number of tasks waiting
+	// for execution. Tasks are rejected with ErrQueueFull
+	// when the queue is full. Zero disables queueing.
+	QueueSize int
+}
+
+// Dispatcher limits the number of concurrently executing
+// tasks. Tasks that cannot be executed immediately wait in
+// a first-in, first-out queue.
+type Dispatcher struct {
+	handler task.Handler
+	config  Config
+
+	mu      sync.Mutex
+	running int
+	types   map[string]int
+	queue   *list.List
+}
+
+// waiter is a task waiting in the queue.
+type waiter struct {
+	typ   string
+	ready chan struct{}
+}
+
+// New returns a dispatcher that executes tasks using
+// the provided handler.
+func New(handler task.Handler, config Config) *Dispatcher {
+	return &Dispatcher{
+		handler: handler,
+		config:  config,
+		types:   map[string]int{},
+		queue:   list.New(),
+	}
+}
+
+// Handle executes the task request once a slot is free.
+// The time spent waiting in the queue is added to the
+// logger fields.
+func (d *Dispatcher) Handle(ctx context.Context, req *task.Request) task.Response {
+	typ := req.Task.Type
+	start := time.Now()
+	if err := d.acquire(ctx, typ); err != nil {
+		return task.Error(err)
+	}
+	defer d.release(typ)
+
+	log := logger.FromContext(ctx).
+		WithField("queue.wait", time.Since(start).String())
+	return d.handler.Handle(logger.WithContext(ctx, log), req)
+}
+
+// Stats returns the number of running and queued tasks.
+func (d *Dispatcher) Stats() (running, queued int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.running, d.queue.Len()
+}
+
+// acquire blocks until the task can be executed.
+func (d *Dispatcher) acquire(ctx context.Context, typ string) error {
+	d.mu.Lock()
+	// queued tasks are never eligible for execution, as the
+	// queue is dispatched each time a slot is released, so
+	// the task is executed immediately if a slot is free.
+	if d.available(typ) {
+		d.start(typ)
+		d.mu.Unlock()
+		return nil
+	}
+	if d.queue.Len() >= d.config.QueueSize {
+		d.mu.Unlock()
+		return ErrQueueFull
+	}
+	w := &waiter{typ: typ, ready: make(chan struct{})}
+	elem := d.queue.PushBack(w)
+	d.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		select {
+		case <-w.ready:
+			// the slot was granted while the context was
+			// cancelled, and must be released.
+			d.stop(typ)
+			d.dispatch()
+		default:
+			d.queue.Remove(elem)
+		}
+		return ctx.Err()
+	}
+}
+
+// release releases the slot and dispatches queued tasks.
+func (d *Dispatcher) release(typ string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stop(typ)
+	d.dispatch()
+}
+
+// dispatch starts the queued tasks that are eligible for
+// execution, in first-in, first-out order. The caller must
+// hold the lock.
+func (d *Dispatcher) dispatch() {
+	for elem := d.queue.Front(); elem != nil; {
+		if d.config.Concurrency > 0 && d.running >= d.config.Concurrency {
+			return
+		}
+		next := elem.Next()
+		w := elem.Value.(*waiter)
+		if d.available(w.typ) {
+			d.queue.Remove(elem)
+			d.start(w.typ)
+			close(w.ready)
+		}
+		elem = next
+	}
+}
+
+// available returns true if a slot is free for the task
+// type. The caller must hold the lock.
+func (d *Dispatcher) available(typ string) bool {
+	if d.config.Concurrency > 0 && d.running >= d.config.Concurrency {
+		return false
+	}
+	if limit := d.config.Limits[typ]; limit > 0 && d.types[typ] >= limit {
+		return false
+	}
+	return true
+}
+
+func (d *Dispatcher) start(typ string) {
+	d.running++
+	d.types[typ]++
+}
+
+func (d *Dispatcher) stop(typ string) {
+	d.running--
+	d.types[typ]--
+	if d.types[typ] == 0 {
+		delete(d.types, typ)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+)
+
+// blocker is a handler that blocks until released and
+// records the order in which tasks started.
+type blocker struct {
+	mu      sync.Mutex
+	started []string
+	release chan struct{}
+}
+
+func (b *blocker) Handle(_ context.Context, req *task.Request) task.Response {
+	b.mu.Lock()
+	b.started = append(b.started, req.Task.ID)
+	b.mu.Unlock()
+	<-b.release
+	return task.Respond("ok")
+}
+
+func (b *blocker) order() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.started...)
+}
+
+// waitFor waits until the dispatcher reports the expected
+// number of running and queued tasks.
+func waitFor(t *testing.T, d *Dispatcher, running, queued int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if r, q := d.Stats(); r == running && q == queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	r, q := d.Stats()
+	t.Fatalf("Want %d running and %d queued, got %d and %d", running, queued, r, q)
+}
+
+func TestDispatcher(t *testing.T) {
+	b := &blocker{release: make(chan struct{})}
+	d := New(b, Config{Concurrency: 1, QueueSize: 2})
+
+	var wg sync.WaitGroup
+	submit := func(id string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Handle(context.Background(), &task.Request{Task: &task.Task{ID: id, Type: "ping"}})
+		}()
+	}
+
+	submit("1")
+	waitFor(t, d, 1, 0)
+	submit("2")
+	waitFor(t, d, 1, 1)
+	submit("3")
+	waitFor(t, d, 1, 2)
+
+	res := d.Handle(context.Background(), &task.Request{Task: &task.Task{ID: "4", Type: "ping"}})
+	if !errors.Is(res.Error(), ErrQueueFull) {
+		t.Errorf("Want queue full error, got %v", res.Error())
+	}
+
+	close(b.release)
+	wg.Wait()
+
+	got := b.order()
+	want := []string{"1", "2", "3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Want execution order %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestDispatcher_TypeLimit(t *testing.T) {
+	b := &blocker{release: make(chan struct{})}
+	d := New(b, Config{Concurrency: 3, QueueSize: 10, Limits: map[string]int{"slow": 1}})
+
+	go d.Handle(context.Background(), &task.Request{Task: &task.Task{ID: "1", Type: "slow"}})
+	waitFor(t, d, 1, 0)
+	go d.Handle(context.Background(), &task.Request{Task: &task.Task{ID: "2", Type: "slow"}})
+	waitFor(t, d, 1, 1)
+
+	// a task of another type is not blocked by the queued
+	// task that is waiting for the type limit.
+	go d.Handle(context.Background(), &task.Request{Task: &task.Task{ID: "3", Type: "fast"}})
+	waitFor(t, d, 2, 1)
+
+	close(b.release)
+	waitFor(t, d, 0, 0)
+}
+
+func TestDispatcher_Cancel(t *testing.T) {
+	b := &blocker{release: make(chan struct{})}
+	defer close(b.release)
+	d := New(b, Config{Concurrency: 1, QueueSize: 1})
+
+	go d.Handle(context.Background(), &task.Request{Task: &task.Task{ID: "1"}})
+	waitFor(t, d, 1, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res := d.Handle(ctx, &task.Request{Task: &task.Task{ID: "2"}})
+	if !errors.Is(res.Error(), context.DeadlineExceeded) {
+		t.Errorf("Want deadline exceeded, got %v", res.Error())
+	}
+	waitFor(t, d, 1, 0)
+}
@@ -20,18 +20,29 @@ type Downloader struct {
 	executableDownloader *executableDownloader
 }
 
-func New(cloner cloner.Cloner, dir string) Downloader {
-	repoDownloader := newRepoDownloader(cloner)
-	executableDownloader := newExecutableDownloader()
+// New returns a Downloader that downloads artifacts to the
+// directory. The observer is notified of every download, and
+// may be nil.
+func New(cloner cloner.Cloner, dir string, observer Observer) Downloader {
+	repoDownloader := newRepoDownloader(cloner, observer)
+	executableDownloader := newExecutableDownloader(observer)
 	return Downloader{dir: dir, repoDownloader: repoDownloader, executableDownloader: executableDownloader}
 }
 
 func (d *Downloader) DownloadRepo(ctx context.Context, repo *task.Repository) (string, error) {
-	return d.repoDownloader.download(ctx, d.dir, repo)
+	path, err := d.repoDownloader.download(ctx, d.dir, repo)
+	if err != nil {
+		return "", task.Fail(task.CodeDownload, task.PhaseDownload, "failed to download repository", err)
+	}
+	return path, nil
 }
 
 func (d *Downloader) DownloadExecutable(ctx context.Context, taskType string, exec *task.ExecutableConfig, fallbackEnabled bool, envs map[string]string) (string, error) {
-	return d.executableDownloader.download(ctx, d.dir, taskType, exec, fallbackEnabled, envs)
+	path, err := d.executableDownloader.download(ctx, d.dir, taskType, exec, fallbackEnabled, envs)
+	if err != nil {
+		return "", task.Fail(task.CodeDownload, task.PhaseDownload, "failed to download executable", err)
+	}
+	return path, nil
 }
 
 func (d *Downloader) GetDir() string {
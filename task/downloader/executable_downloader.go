@@ -5,7 +5,6 @@ package downloader
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -13,7 +12,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/klauspost/compress/zstd"
 
@@ -30,13 +28,24 @@ var chmodFn = os.Chmod
 
 // executableDownloader a binary executable file
 // It also takes care of where to download the file
-type executableDownloader struct{}
+type executableDownloader struct {
+	observer Observer
+}
 
-func newExecutableDownloader() *executableDownloader {
-	return &executableDownloader{}
+func newExecutableDownloader(observer Observer) *executableDownloader {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+	return &executableDownloader{observer: observer}
 }
 
-func (e *executableDownloader) download(ctx context.Context, dir string, taskType string, exec *task.ExecutableConfig, fallbackEnabled bool, envs map[string]string) (string, error) {
+func (e *executableDownloader) download(ctx context.Context, dir string, taskType string, exec *task.ExecutableConfig, fallbackEnabled bool, envs map[string]string) (_ string, err error) {
+	d := &Download{Kind: KindExecutable, TaskType: taskType, Executable: exec}
+	ctx = e.observer.Start(ctx, d)
+	defer func() {
+		e.observer.Finish(ctx, d, err)
+	}()
+
 	if exec == nil {
 		return "", errors.New("no executable urls provided to download")
 	}
@@ -46,6 +55,7 @@ func (e *executableDownloader) download(ctx context.Context, dir string, taskTyp
 	if !ok {
 		return "", fmt.Errorf("os [%s] and architecture [%s] are not specified in executable configuration", operatingSystem, architecture)
 	}
+	d.URLs = urls
 
 	var destDir, dest string
 
@@ -57,8 +67,10 @@ func (e *executableDownloader) download(ctx context.Context, dir string, taskTyp
 	} else {
 		dest = expandWithMapAndEnv(exec.Target, envs, 3)
 	}
-	if cacheHit := isCacheHitFn(ctx, dest); cacheHit {
+	d.CacheHit = isCacheHitFn(ctx, dest)
+	if d.CacheHit {
 		// exit if the artifact destination already exists
+		d.Path = dest
 		return dest, nil
 	}
 
@@ -82,7 +94,6 @@ func (e *executableDownloader) download(ctx context.Context, dir string, taskTyp
 		}
 		return "", err
 	}
-	e.logExecutableDownload(ctx, exec, operatingSystem, architecture)
 
 	if exec.Compressed {
 		binPath, err = decompressFile(ctx, binPath)
@@ -94,6 +105,7 @@ func (e *executableDownloader) download(ctx context.Context, dir string, taskTyp
 	if err = chmodFn(binPath, 0777); err != nil {
 		return "", fmt.Errorf("failed to set executable flag in task file [%s]: %w", binPath, err)
 	}
+	d.Path = binPath
 	return binPath, nil
 }
 
@@ -175,26 +187,3 @@ func (e *executableDownloader) getExecutableUrl(config *task.ExecutableConfig, o
 	}
 	return urls, len(urls) > 0
 }
-
-// logExecutableDownload writes details about the Executable struct used to download a task's executable file
-func (e *executableDownloader) logExecutableDownload(ctx context.Context, exec *task.ExecutableConfig, operatingSystem, architecture string) {
-	log := logger.FromContext(ctx)
-	filename := "executable_downloads.log"
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Error(fmt.Sprintf("Failed to open log file [%s]: %v", filename, err))
-	}
-	defer file.Close()
-
-	// Convert the struct to JSON
-	data, err := json.Marshal(exec)
-	if err != nil {
-		log.Error(fmt.Sprintf("Failed to marshall Executable struct to json: %v", err))
-	}
-
-	entry := fmt.Sprintf("%s: dowloaded for os: [%s], arch: [%s] %s\n", time.Now().Format(time.RFC3339), operatingSystem, architecture, string(data))
-	// Write the JSON string to the file, followed by a newline
-	if _, err := file.WriteString(entry); err != nil {
-		log.Error(fmt.Sprintf("Failed to write Executable struct to log file [%s]: %v", filename, err))
-	}
-}
@@ -31,7 +31,7 @@ func TestDownloadExecutable(t *testing.T) {
 		return nil
 	}
 
-	downloader := newExecutableDownloader()
+	downloader := newExecutableDownloader(nil)
 
 	tests := []struct {
 		name        string
@@ -127,8 +127,57 @@ func TestDownloadExecutable(t *testing.T) {
 	}
 }
 
+func TestDownloadExecutable_Observer(t *testing.T) {
+	originalIsCacheHitFn := isCacheHitFn
+	defer func() { isCacheHitFn = originalIsCacheHitFn }()
+	isCacheHitFn = func(ctx context.Context, dest string) bool {
+		return true
+	}
+
+	dir := t.TempDir()
+	exec := &task.ExecutableConfig{
+		Name:    "plugin",
+		Version: "1.0.0",
+		Executables: []task.Executable{
+			{Os: runtime.GOOS, Arch: runtime.GOARCH, Url: "https://example.com/plugin"},
+		},
+	}
+	observer := new(recorder)
+	path, err := newExecutableDownloader(observer).download(context.Background(), dir, "binary", exec, false, nil)
+	assert.NoError(t, err)
+
+	want := &Download{
+		Kind:       KindExecutable,
+		TaskType:   "binary",
+		Executable: exec,
+		URLs:       []string{"https://example.com/plugin"},
+		Path:       path,
+		CacheHit:   true,
+	}
+	assert.Equal(t, want, observer.started)
+	assert.Equal(t, want, observer.finished)
+	assert.NoError(t, observer.err)
+}
+
+// recorder is an Observer that records the download.
+type recorder struct {
+	started  *Download
+	finished *Download
+	err      error
+}
+
+func (r *recorder) Start(ctx context.Context, d *Download) context.Context {
+	r.started = d
+	return ctx
+}
+
+func (r *recorder) Finish(_ context.Context, d *Download, err error) {
+	r.finished = d
+	r.err = err
+}
+
 func TestGetExecutableUrl(t *testing.T) {
-	downloader := newExecutableDownloader()
+	downloader := newExecutableDownloader(nil)
 
 	tests := []struct {
 		name            string
@@ -0,0 +1,63 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"context"
+
+	"github.com/drone/go-task/task"
+)
+
+// kinds of downloaded artifacts.
+const (
+	KindRepo       = "repo"
+	KindExecutable = "executable"
+)
+
+// Download describes the download of a task artifact.
+type Download struct {
+	// Kind provides the kind of artifact, repo or
+	// executable.
+	Kind string
+
+	// TaskType provides the task type of an executable.
+	TaskType string
+
+	// Repository provides the downloaded repository.
+	Repository *task.Repository
+
+	// Executable provides the downloaded executable, and
+	// URLs provides the executable download urls for the
+	// platform.
+	Executable *task.ExecutableConfig
+	URLs       []string
+
+	// Path provides the local path of the artifact, once
+	// the artifact is downloaded or found in the cache.
+	Path string
+
+	// CacheHit reports whether the artifact was found in
+	// the cache.
+	CacheHit bool
+}
+
+// Observer observes artifact downloads, for example to
+// record metrics, trace spans and audit entries.
+type Observer interface {
+	// Start is called before the artifact is downloaded,
+	// and returns the context used for the download.
+	Start(ctx context.Context, download *Download) context.Context
+
+	// Finish is called with the context returned by Start
+	// once the download completes, and the error if the
+	// download failed.
+	Finish(ctx context.Context, download *Download, err error)
+}
+
+// nopObserver is an Observer that does nothing.
+type nopObserver struct{}
+
+func (nopObserver) Start(ctx context.Context, _ *Download) context.Context { return ctx }
+func (nopObserver) Finish(context.Context, *Download, error)               {}
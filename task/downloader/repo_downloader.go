@@ -21,29 +21,42 @@ import (
 )
 
 type repoDownloader struct {
-	cloner cloner.Cloner
+	cloner   cloner.Cloner
+	observer Observer
 }
 
 // repoDownloader downloads a repository
 // It also takes care of where to download the repository
-func newRepoDownloader(cloner cloner.Cloner) *repoDownloader {
-	return &repoDownloader{cloner: cloner}
+func newRepoDownloader(cloner cloner.Cloner, observer Observer) *repoDownloader {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+	return &repoDownloader{cloner: cloner, observer: observer}
 }
 
-func (r *repoDownloader) download(ctx context.Context, dir string, repo *task.Repository) (string, error) {
+func (r *repoDownloader) download(ctx context.Context, dir string, repo *task.Repository) (_ string, err error) {
 	if repo == nil {
 		return "", errors.New("no repository provided to download")
 	}
 	dest := r.getDownloadDir(dir, repo)
 
-	if cacheHit := isCacheHitFn(ctx, dest); cacheHit {
+	d := &Download{Kind: KindRepo, Repository: repo, Path: dest}
+	ctx = r.observer.Start(ctx, d)
+	defer func() {
+		r.observer.Finish(ctx, d, err)
+	}()
+
+	d.CacheHit = isCacheHitFn(ctx, dest)
+	if d.CacheHit {
 		// exit if the destination already exists
 		return dest, nil
 	}
 	if repo.Download != "" {
-		return dest, r.downloadRepo(ctx, repo, dest)
+		err = r.downloadRepo(ctx, repo, dest)
+	} else {
+		err = r.clone(ctx, repo, dest)
 	}
-	return dest, r.clone(ctx, repo, dest)
+	return dest, err
 }
 
 func (r *repoDownloader) clone(ctx context.Context, repo *task.Repository, dest string) error {
@@ -32,7 +32,7 @@ func TestDownload(t *testing.T) {
 	defer func() { isCacheHitFn = originalIsCacheHitFn }() // Restore after the test
 
 	mockCloner := new(MockCloner)
-	downloader := newRepoDownloader(mockCloner)
+	downloader := newRepoDownloader(mockCloner, nil)
 
 	tests := []struct {
 		name     string
@@ -83,7 +83,7 @@ func TestDownload(t *testing.T) {
 
 func TestClone(t *testing.T) {
 	mockCloner := new(MockCloner)
-	downloader := newRepoDownloader(mockCloner)
+	downloader := newRepoDownloader(mockCloner, nil)
 
 	repo := &task.Repository{
 		Clone: "https://github.com/user/repo.git",
@@ -100,7 +100,7 @@ func TestClone(t *testing.T) {
 }
 
 func TestGetDownloadDir(t *testing.T) {
-	downloader := newRepoDownloader(nil)
+	downloader := newRepoDownloader(nil, nil)
 
 	repo := &task.Repository{
 		Clone: "https://github.com/user/repo.git",
@@ -18,7 +18,7 @@ import (
 // functions for mocking
 var (
 	mkdirAllFn     = os.MkdirAll
-	httpGetFn      = http.Get
+	httpGetFn      = httpGet
 	createFn       = os.Create
 	copyFn         = io.Copy
 	getcacheFn     = os.UserCacheDir
@@ -44,7 +44,7 @@ func downloadFile(ctx context.Context, urls []string, dest string) (string, erro
 			"destination": dest,
 		}).Debug("attempting to download artifact")
 
-		resp, err := httpGetFn(u)
+		resp, err := httpGetFn(ctx, u)
 		if err != nil {
 			lastErr = fmt.Errorf("failed to download file from %s: %w", u, err)
 			log.WithError(lastErr).Warn("download attempt failed")
@@ -80,6 +80,16 @@ func downloadFile(ctx context.Context, urls []string, dest string) (string, erro
 	return "", fmt.Errorf("failed to download file from all provided urls: %w", lastErr)
 }
 
+// httpGet issues a GET to the specified url. The request
+// is cancelled when the context is cancelled.
+func httpGet(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
 // getDownloadPath returns the full download path given the download url and the destination folder `dest`
 func getDownloadPath(url, dest string) string {
 	fileName := filepath.Base(url)
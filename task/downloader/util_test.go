@@ -37,14 +37,14 @@ func TestDownloadFile(t *testing.T) {
 		dest          string
 		fileCreateErr bool
 		wantErr       bool
-		mockGetFn     func(string) (*http.Response, error)
+		mockGetFn     func(context.Context, string) (*http.Response, error)
 	}{
 		{
 			name:    "successful_download",
 			url:     "http://example.com/file.txt",
 			dest:    "/tmp/testfile.txt",
 			wantErr: false,
-			mockGetFn: func(url string) (*http.Response, error) {
+			mockGetFn: func(ctx context.Context, url string) (*http.Response, error) {
 				body := io.NopCloser(strings.NewReader("mock file content"))
 				return &http.Response{
 					StatusCode: http.StatusOK,
@@ -57,7 +57,7 @@ func TestDownloadFile(t *testing.T) {
 			url:     "http://example.com/nonexistent",
 			dest:    "/tmp/testfile.txt",
 			wantErr: true,
-			mockGetFn: func(url string) (*http.Response, error) {
+			mockGetFn: func(ctx context.Context, url string) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusNotFound,
 					Body:       io.NopCloser(strings.NewReader("")),
@@ -70,7 +70,7 @@ func TestDownloadFile(t *testing.T) {
 			dest:          "/invalid/dir/testfile.txt",
 			fileCreateErr: true,
 			wantErr:       true,
-			mockGetFn: func(url string) (*http.Response, error) {
+			mockGetFn: func(ctx context.Context, url string) (*http.Response, error) {
 				body := io.NopCloser(strings.NewReader("mock file content"))
 				return &http.Response{
 					StatusCode: http.StatusOK,
@@ -8,9 +8,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/drone/go-task/task/audit"
 	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/masker"
 	"github.com/drone/go-task/task/packaged"
 
 	"github.com/drone/go-task/task"
@@ -22,6 +27,14 @@ var (
 	taskYmlPath = "task.yml"
 )
 
+// response headers used by the CGI task to return named
+// outputs and secrets, in the format name=value. The
+// headers can be repeated.
+const (
+	outputHeader = "X-Task-Output"
+	secretHeader = "X-Task-Secret"
+)
+
 // Config provides the driver config.
 type Config struct {
 	ExecutableConfig *task.ExecutableConfig `json:"executable_config"`
@@ -30,16 +43,71 @@ type Config struct {
 	Endpoint         string                 `json:"endpoint"`
 	Headers          map[string]string      `json:"headers"`
 	Envs             []string               `json:"envs"`
+
+	// Timeout provides the execution timeout in seconds,
+	// including the artifact download and build.
+	Timeout int `json:"timeout"`
+
+	// MaxLogSize provides the limit, in bytes, of the
+	// process stderr written to the task log. Defaults
+	// to 5 MiB.
+	MaxLogSize int `json:"max_log_size"`
+
+	// Env provides the policy for inheriting the runner
+	// environment. The policy can only restrict the
+	// variables allowed by the driver policy.
+	Env *EnvPolicy `json:"env"`
+
+	// SecretEnvs provides the secrets injected into the
+	// process environment, as a map of variable name to
+	// secret id. Secret values are masked in the task log.
+	SecretEnvs map[string]string `json:"secret_envs"`
+
+	// Limits provides the resource limits of the process.
+	Limits *Limits `json:"limits"`
+
+	// Network provides the network endpoints, in host:port
+	// format, that the process can connect to when the
+	// driver isolates the process. Tcp connections are
+	// restricted to the endpoint ports, which requires
+	// linux 6.7 or later. If empty, the isolated process
+	// has no network access.
+	Network []string `json:"network"`
+}
+
+// Options provides the driver options.
+type Options struct {
+	// Env provides the policy for inheriting the runner
+	// environment. Defaults to the DefaultEnvAllowlist.
+	Env *EnvPolicy
+
+	// Sandbox provides the per-execution working directory
+	// and user of the process. If nil, the process runs as
+	// the runner user in the directory of the binary.
+	Sandbox *Sandbox
 }
 
-// New returns the task execution driver.
+// New returns the task execution driver. The CGI process
+// inherits the runner environment variables in the
+// DefaultEnvAllowlist.
 func New(d downloader.Downloader, pl packaged.PackageLoader) task.Handler {
-	return &driver{downloader: d, packageLoader: pl}
+	return NewWithOptions(d, pl, Options{})
+}
+
+// NewWithOptions returns the task execution driver with
+// the options.
+func NewWithOptions(d downloader.Downloader, pl packaged.PackageLoader, opts Options) task.Handler {
+	if opts.Env == nil {
+		opts.Env = &EnvPolicy{Mode: EnvAllowlist}
+	}
+	return &driver{downloader: d, packageLoader: pl, envPolicy: opts.Env, sandbox: opts.Sandbox}
 }
 
 type driver struct {
 	downloader    downloader.Downloader
 	packageLoader packaged.PackageLoader
+	envPolicy     *EnvPolicy
+	sandbox       *Sandbox
 }
 
 // Handle handles the task execution request.
@@ -50,13 +118,32 @@ func (d *driver) Handle(ctx context.Context, req *task.Request) task.Response {
 	// decode the task configuration
 	err := json.Unmarshal(req.Task.Config, conf)
 	if err != nil {
-		return task.Error(err)
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "invalid driver configuration", err))
+	}
+
+	if conf.Env != nil {
+		if err := conf.Env.validate(); err != nil {
+			return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "invalid driver configuration", err))
+		}
+	}
+	if err := conf.Limits.validate(); err != nil {
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "invalid driver configuration", err))
+	}
+	secretEnvs, err := secretEnv(conf.SecretEnvs, req.Secrets)
+	if err != nil {
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "invalid driver configuration", err))
+	}
+
+	if conf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = task.WithTimeout(ctx, time.Duration(conf.Timeout)*time.Second)
+		defer cancel()
 	}
 
 	path, err := d.prepareArtifact(ctx, req.Task.Type, conf)
 	if err != nil {
 		log.WithError(err).Error("Prepare artifact failed")
-		return task.Error(err)
+		return fail(ctx, task.PhaseDownload, err)
 	}
 
 	setDefaultConfigValues(conf)
@@ -64,17 +151,77 @@ func (d *driver) Handle(ctx context.Context, req *task.Request) task.Response {
 	binPath, err := d.getBinaryPath(ctx, path, conf)
 	if err != nil {
 		log.WithError(err).Error("task build failed")
-		return task.Error(err)
+		return fail(ctx, task.PhaseBuild, err)
 	}
 
 	execer := newExecer(binPath, conf)
+	execer.Environ = inherit(os.Environ(), d.envPolicy, conf.Env)
+	execer.Secrets = secretEnvs
+	execer.Sandbox = d.sandbox
+	// stream the process stderr to the task log, masking
+	// the secrets available to the task.
+	if req.Logger != nil {
+		execer.Logger = masker.New(req.Logger, masker.Slice(req.Secrets))
+	}
 	resp, err := execer.Exec(ctx, req.Task.Data)
 	if err != nil {
 		log.WithError(err).Error("could not execute cgi task")
+		return fail(ctx, task.PhaseExec, err)
+	}
+
+	// extract the named outputs and secrets from the
+	// response headers, so that secrets are not returned
+	// in the response envelope.
+	outputs := extractHeader(resp.Headers, outputHeader)
+	secrets := extractHeader(resp.Headers, secretHeader)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
 		return task.Error(err)
 	}
+	return &task.Result{Data: data, Outputs: outputs, Secrets: secrets}
+}
+
+// extractHeader removes the header from the response
+// headers and returns the name=value pairs as a map.
+func extractHeader(headers map[string][]string, key string) map[string]string {
+	values, ok := headers[key]
+	if !ok {
+		return nil
+	}
+	delete(headers, key)
 
-	return task.Respond(resp)
+	out := map[string]string{}
+	for _, value := range values {
+		name, value, ok := strings.Cut(value, "=")
+		if name = strings.TrimSpace(name); ok && name != "" {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// fail returns an error response. A timeout error is
+// returned if the deadline was exceeded during the phase,
+// an existing Failure is returned unchanged, else a Failure
+// for the phase.
+func fail(ctx context.Context, phase task.Phase, err error) task.Response {
+	if timeoutErr := task.CheckTimeout(ctx, phase); timeoutErr != nil {
+		return task.Error(timeoutErr)
+	}
+	// the downloader and builder return a Failure with a
+	// more specific message, which is passed through.
+	if errors.As(err, new(*task.Failure)) {
+		return task.Error(err)
+	}
+	switch phase {
+	case task.PhaseDownload:
+		return task.Error(task.Fail(task.CodeDownload, phase, "failed to prepare task artifact", err))
+	case task.PhaseBuild:
+		return task.Error(task.Fail(task.CodeBuild, phase, "failed to build task", err))
+	default:
+		return task.Error(task.Fail(task.CodeExec, phase, "failed to execute task", err))
+	}
 }
 
 func (d *driver) prepareArtifact(ctx context.Context, taskType string, conf *Config) (string, error) {
@@ -86,6 +233,13 @@ func (d *driver) prepareArtifact(ctx context.Context, taskType string, conf *Con
 		} else {
 			log := logger.FromContext(ctx)
 			log.WithField("path", cgiPath).Info("using prepackaged binary")
+			err := audit.RecordFile(ctx, &audit.Artifact{
+				Name:    conf.ExecutableConfig.Name,
+				Version: conf.ExecutableConfig.Version,
+			}, cgiPath)
+			if err != nil {
+				log.WithError(err).Warn("cannot hash executable for audit log")
+			}
 			return cgiPath, nil
 		}
 	} else if conf.Repository != nil {
@@ -122,3 +276,16 @@ func (d *driver) getBinaryPath(ctx context.Context, path string, conf *Config) (
 	builder := builder.New(filepath.Join(path, taskYmlPath))
 	return builder.Build(ctx)
 }
+
+// Describe returns the driver metadata.
+func (d *driver) Describe() *task.Metadata {
+	return &task.Metadata{
+		Description: "Downloads, builds and executes the task as a CGI executable.",
+	}
+}
+
+// Decode decodes the task output from the CGITaskResponse
+// envelope returned by the driver.
+func (d *driver) Decode(res task.Response) ([]byte, error) {
+	return task.DecodeCGIResponse(res)
+}
@@ -3,3 +3,218 @@
 // license that can be found in the LICENSE file.
 
 package cgi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/audit"
+	"github.com/drone/go-task/task/common"
+	"github.com/drone/go-task/task/downloader"
+	"github.com/drone/go-task/task/packaged"
+)
+
+// testDriver returns a driver that executes the script
+// as a prepackaged binary for the task type.
+func testDriver(t *testing.T, taskType, script string) task.Handler {
+	t.Helper()
+	path := testScript(t, script)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, taskType, "test"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, filepath.Join(dir, taskType, "test", "task.sh")); err != nil {
+		t.Fatal(err)
+	}
+	return New(downloader.New(nil, t.TempDir(), nil), packaged.New(dir))
+}
+
+// testConfig returns the encoded driver configuration.
+func testConfig(conf *Config) []byte {
+	conf.ExecutableConfig = &task.ExecutableConfig{Name: "test"}
+	b, _ := json.Marshal(conf)
+	return b
+}
+
+func TestDriver_Timeout(t *testing.T) {
+	d := testDriver(t, "sleep", "exec sleep 30\n")
+	res := d.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:   "sleep",
+			Config: testConfig(&Config{Timeout: 1}),
+		},
+	})
+	timeoutErr := new(task.TimeoutError)
+	if !errors.As(res.Error(), &timeoutErr) {
+		t.Fatalf("Want timeout error, got %v", res.Error())
+	}
+	if got, want := timeoutErr.Phase, task.PhaseExec; got != want {
+		t.Errorf("Want timeout phase %s, got %s", want, got)
+	}
+}
+
+func TestDriver_Outputs(t *testing.T) {
+	d := testDriver(t, "outputs", `
+echo "Content-Type: application/json"
+echo "X-Task-Output: version=1.2.3"
+echo "X-Task-Output: digest=sha256:abc=="
+echo "X-Task-Secret: token=s3cr3t"
+echo ""
+echo "{}"
+`)
+	res := d.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:   "outputs",
+			Config: testConfig(&Config{}),
+		},
+	})
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	wantOutputs := map[string]string{"version": "1.2.3", "digest": "sha256:abc=="}
+	if got := task.Outputs(res); !reflect.DeepEqual(got, wantOutputs) {
+		t.Errorf("Want outputs %v, got %v", wantOutputs, got)
+	}
+	wantSecrets := map[string]string{"token": "s3cr3t"}
+	if got := task.Secrets(res); !reflect.DeepEqual(got, wantSecrets) {
+		t.Errorf("Want secrets %v, got %v", wantSecrets, got)
+	}
+	if bytes.Contains(res.Body(), []byte("s3cr3t")) {
+		t.Errorf("Expect secrets removed from the response envelope")
+	}
+}
+
+func TestDriver_AuditPackaged(t *testing.T) {
+	d := testDriver(t, "packaged", `
+echo "Content-Type: text/plain"
+echo ""
+`)
+	var entry *audit.Entry
+	sink := sinkFunc(func(_ context.Context, e *audit.Entry) error {
+		entry = e
+		return nil
+	})
+	res := audit.Handler(sink)(d).Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:   "packaged",
+			Config: testConfig(&Config{}),
+		},
+	})
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Artifacts) != 1 {
+		t.Fatalf("Want prepackaged binary recorded, got %v", entry.Artifacts)
+	}
+	artifact := entry.Artifacts[0]
+	if artifact.Name != "test" || filepath.Base(artifact.Path) != "task.sh" || len(artifact.SHA256) != 64 {
+		t.Errorf("Want prepackaged binary path and hash recorded, got %+v", artifact)
+	}
+}
+
+// sinkFunc adapts a function to the audit Sink interface.
+type sinkFunc func(context.Context, *audit.Entry) error
+
+func (fn sinkFunc) Write(ctx context.Context, entry *audit.Entry) error {
+	return fn(ctx, entry)
+}
+
+func TestDriver_Logs(t *testing.T) {
+	d := testDriver(t, "logs", `
+echo "token is s3cr3t" >&2
+echo "Content-Type: text/plain"
+echo ""
+`)
+	buf := new(bytes.Buffer)
+	res := d.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:   "logs",
+			Config: testConfig(&Config{}),
+		},
+		Secrets: []*common.Secret{{ID: "token", Value: "s3cr3t"}},
+		Logger:  buf,
+	})
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), " token is [redacted]\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("Want masked task log %q, got %q", want, got)
+	}
+}
+
+func TestDriver_Env(t *testing.T) {
+	t.Setenv("HOME", "/home/runner")
+	t.Setenv("RUNNER_TOKEN", "runner-s3cr3t")
+
+	d := testDriver(t, "env", `
+echo "Content-Type: text/plain"
+echo "X-Home: $HOME"
+echo "X-Runner-Token: $RUNNER_TOKEN"
+echo "X-Token: $TOKEN"
+echo ""
+echo "$TOKEN" >&2
+`)
+	buf := new(bytes.Buffer)
+	res := d.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:   "env",
+			Config: testConfig(&Config{SecretEnvs: map[string]string{"TOKEN": "token"}}),
+		},
+		Secrets: []*common.Secret{{ID: "token", Value: "s3cr3t"}},
+		Logger:  buf,
+	})
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	resp := new(task.CGITaskResponse)
+	json.Unmarshal(res.Body(), resp)
+
+	want := map[string]string{
+		"X-Home":         "/home/runner",
+		"X-Runner-Token": "",
+		"X-Token":        "s3cr3t",
+	}
+	for key, value := range want {
+		if got := resp.Headers[key]; len(got) != 1 || got[0] != value {
+			t.Errorf("Want header %s=%q, got %v", key, value, got)
+		}
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("Expect secret env masked in the task log")
+	}
+}
+
+func TestDriver_EnvMissingSecret(t *testing.T) {
+	d := testDriver(t, "env", "exit 1\n")
+	res := d.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:   "env",
+			Config: testConfig(&Config{SecretEnvs: map[string]string{"TOKEN": "token"}}),
+		},
+	})
+	if got, want := task.AsFailure(res.Error()).Code, task.CodeInvalid; got != want {
+		t.Errorf("Want failure code %s, got %s", want, got)
+	}
+}
+
+func TestFail(t *testing.T) {
+	want := task.Fail(task.CodeDownload, task.PhaseDownload, "failed to download repository", errors.New("exit status 128"))
+	res := fail(context.Background(), task.PhaseDownload, want)
+	if got := res.Error(); got != want {
+		t.Errorf("Want existing failure returned unchanged, got %v", got)
+	}
+
+	res = fail(context.Background(), task.PhaseBuild, errors.New("exit status 1"))
+	failure := task.AsFailure(res.Error())
+	if failure.Code != task.CodeBuild || failure.Message != "failed to build task" {
+		t.Errorf("Want build failure, got %+v", failure)
+	}
+}
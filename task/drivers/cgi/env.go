@@ -0,0 +1,149 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/drone/go-task/task/common"
+)
+
+// EnvMode defines how the CGI process inherits the
+// runner environment.
+type EnvMode string
+
+// EnvMode enumeration.
+const (
+	EnvNone      EnvMode = "none"      // inherit no variables
+	EnvAllowlist EnvMode = "allowlist" // inherit allowed variables
+	EnvAll       EnvMode = "all"       // inherit all variables
+)
+
+// DefaultEnvAllowlist provides the runner environment
+// variables inherited by default. The list excludes
+// variables that commonly hold credentials.
+var DefaultEnvAllowlist = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"LANG",
+	"LC_*",
+	"TZ",
+	"TMPDIR",
+	"XDG_CACHE_HOME",
+	"SSL_CERT_FILE",
+	"SSL_CERT_DIR",
+	"HTTP_PROXY",
+	"HTTPS_PROXY",
+	"NO_PROXY",
+	"http_proxy",
+	"https_proxy",
+	"no_proxy",
+	"SYSTEMROOT",
+	"TEMP",
+	"TMP",
+}
+
+// EnvPolicy defines the runner environment variables
+// inherited by the CGI process.
+type EnvPolicy struct {
+	// Mode provides the policy mode. Defaults to
+	// allowlist.
+	Mode EnvMode `json:"mode"`
+
+	// Allow provides the names of the variables that
+	// are inherited in allowlist mode. A name with a
+	// trailing * matches variables by prefix. Defaults
+	// to DefaultEnvAllowlist.
+	Allow []string `json:"allow"`
+}
+
+// allows returns true if the policy allows the variable.
+func (p *EnvPolicy) allows(key string) bool {
+	switch p.Mode {
+	case EnvAll:
+		return true
+	case EnvNone:
+		return false
+	}
+	allow := p.Allow
+	if len(allow) == 0 {
+		allow = DefaultEnvAllowlist
+	}
+	for _, name := range allow {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns an error if the policy mode is not
+// recognized.
+func (p *EnvPolicy) validate() error {
+	switch p.Mode {
+	case "", EnvNone, EnvAllowlist, EnvAll:
+		return nil
+	default:
+		return fmt.Errorf("invalid env policy mode %q", p.Mode)
+	}
+}
+
+// inherit returns the variables in environ that are
+// allowed by every policy. Nil policies are ignored.
+func inherit(environ []string, policies ...*EnvPolicy) []string {
+	var out []string
+	for _, env := range environ {
+		key, _, _ := strings.Cut(env, "=")
+		allowed := true
+		for _, policy := range policies {
+			if policy != nil && !policy.allows(key) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			out = append(out, env)
+		}
+	}
+	return out
+}
+
+// secretEnv returns the environment variables for the
+// secrets, given a map of variable name to secret id. An
+// error is returned if a secret is not found.
+func secretEnv(names map[string]string, secrets []*common.Secret) ([]string, error) {
+	keys := make([]string, 0, len(names))
+	for key := range names {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out []string
+	for _, key := range keys {
+		secret := findSecret(secrets, names[key])
+		if secret == nil {
+			return nil, fmt.Errorf("secret %s not found for env variable %s", names[key], key)
+		}
+		out = append(out, key+"="+secret.Value)
+	}
+	return out, nil
+}
+
+// findSecret returns the secret with the id.
+func findSecret(secrets []*common.Secret, id string) *common.Secret {
+	for _, secret := range secrets {
+		if secret.ID == id {
+			return secret
+		}
+	}
+	return nil
+}
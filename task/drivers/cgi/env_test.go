@@ -0,0 +1,111 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/drone/go-task/task/common"
+)
+
+func TestInherit(t *testing.T) {
+	environ := []string{
+		"HOME=/home/runner",
+		"LC_ALL=C",
+		"AWS_SECRET_ACCESS_KEY=s3cr3t",
+		"FOO=bar",
+	}
+	tests := []struct {
+		name     string
+		policies []*EnvPolicy
+		want     []string
+	}{
+		{
+			name:     "default",
+			policies: []*EnvPolicy{{}},
+			want:     []string{"HOME=/home/runner", "LC_ALL=C"},
+		},
+		{
+			name:     "none",
+			policies: []*EnvPolicy{{Mode: EnvNone}},
+			want:     nil,
+		},
+		{
+			name:     "all",
+			policies: []*EnvPolicy{{Mode: EnvAll}},
+			want:     environ,
+		},
+		{
+			name:     "allowlist",
+			policies: []*EnvPolicy{{Mode: EnvAllowlist, Allow: []string{"FOO", "AWS_*"}}},
+			want:     []string{"AWS_SECRET_ACCESS_KEY=s3cr3t", "FOO=bar"},
+		},
+		{
+			name: "task_restricts_driver",
+			policies: []*EnvPolicy{
+				{Mode: EnvAllowlist, Allow: []string{"HOME", "FOO"}},
+				{Mode: EnvAll},
+			},
+			want: []string{"HOME=/home/runner", "FOO=bar"},
+		},
+		{
+			name: "task_none",
+			policies: []*EnvPolicy{
+				{Mode: EnvAll},
+				{Mode: EnvNone},
+			},
+			want: nil,
+		},
+		{
+			name:     "task_unset",
+			policies: []*EnvPolicy{{Mode: EnvAll}, nil},
+			want:     environ,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := inherit(environ, test.policies...); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Want env %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretEnv(t *testing.T) {
+	secrets := []*common.Secret{
+		{ID: "token", Value: "s3cr3t"},
+		{ID: "password", Value: "correct-horse-battery-staple"},
+	}
+	got, err := secretEnv(map[string]string{"TOKEN": "token", "PASSWORD": "password"}, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"PASSWORD=correct-horse-battery-staple", "TOKEN=s3cr3t"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Want env %v, got %v", want, got)
+	}
+
+	if _, err := secretEnv(map[string]string{"KEY": "missing"}, secrets); err == nil {
+		t.Errorf("Want error for missing secret")
+	}
+}
+
+func TestEnviron_Path(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+
+	// the runner PATH is not used unless inherited.
+	env := environ(req, "/bin/task", inherit([]string{"PATH=/opt/runner/bin"}, &EnvPolicy{Mode: EnvNone}))
+	if !slices.Contains(env, "PATH="+defaultPath) {
+		t.Errorf("Want default PATH, got %v", env)
+	}
+
+	env = environ(req, "/bin/task", inherit([]string{"PATH=/opt/runner/bin"}))
+	if !slices.Contains(env, "PATH=/opt/runner/bin") {
+		t.Errorf("Want inherited PATH, got %v", env)
+	}
+}
@@ -7,21 +7,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/http/cgi"
-	"net/http/httptest"
-	"os"
+	"os/exec"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/drone/go-task/task"
 	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/metrics"
+	"github.com/drone/go-task/task/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// waitDelay bounds the time to wait for the CGI process
+// output to close after the process is killed.
+var waitDelay = 5 * time.Second
+
 type Execer struct {
-	Binpath   string  // path to the binary file for execution
-	CGIConfig *Config // config for the cgi execution
+	Binpath   string    // path to the binary file for execution
+	CGIConfig *Config   // config for the cgi execution
+	Logger    io.Writer // task log to which stderr is streamed
+	Environ   []string  // runner environment inherited by the process
+	Secrets   []string  // secret environment variables
+	Sandbox   *Sandbox  // working directory and user of the process
 }
 
 func newExecer(binpath string, cgiConfig *Config) *Execer {
@@ -31,9 +44,15 @@ func newExecer(binpath string, cgiConfig *Config) *Execer {
 	}
 }
 
-// Exec executes the task given the binary filepath and the configuration
-func (e *Execer) Exec(ctx context.Context, in []byte) (*task.CGITaskResponse, error) {
+// Exec executes the task given the binary filepath and the configuration.
+// The CGI process is killed when the context is cancelled.
+func (e *Execer) Exec(ctx context.Context, in []byte) (_ *task.CGITaskResponse, err error) {
 	conf := e.CGIConfig
+
+	ctx, span := tracing.Start(ctx, "cgi.exec")
+	defer func() {
+		tracing.End(span, err)
+	}()
 	log := logger.FromContext(ctx).WithFields(map[string]interface{}{
 		"cgi.dir":    filepath.Dir(e.Binpath),
 		"cgi.path":   e.Binpath,
@@ -41,24 +60,6 @@ func (e *Execer) Exec(ctx context.Context, in []byte) (*task.CGITaskResponse, er
 		"cgi.url":    conf.Endpoint,
 	})
 
-	// stderrPipe is the reading end of the pipe that will capture anything written to stderr
-	// stderrWriter is the writing end of that pipe
-	// we pass stderrWriter to the CGI handler and read the output from stderrPipe
-	stderrPipe, stderrWriter, err := os.Pipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-	defer stderrPipe.Close()
-
-	handler := cgi.Handler{
-		Path: e.Binpath,
-		Dir:  filepath.Dir(e.Binpath),
-		Env:  append(conf.Envs, os.Environ()...),
-		// the CGI handler reserves the stdout for the HTTP response (technically the application response) and all log messages are supposed to be written to stderr
-		// by default frameworks like logrus, slog writes to stderr
-		Stderr: stderrWriter,
-	}
-
 	// prepare the HTTP request for the handler
 	req, err := http.NewRequestWithContext(ctx, conf.Method, conf.Endpoint, bytes.NewReader(in))
 	if err != nil {
@@ -68,24 +69,127 @@ func (e *Execer) Exec(ctx context.Context, in []byte) (*task.CGITaskResponse, er
 		req.Header.Set(key, value)
 	}
 
-	// record the CGI handler’s response
-	responseRecorder := httptest.NewRecorder()
+	// propagate the trace context to the CGI process
+	// using the traceparent header and env variable.
+	tracing.Inject(ctx, req.Header)
+
+	// the CGI process reserves the stdout for the HTTP response (technically the application response) and all log messages are supposed to be written to stderr
+	// by default frameworks like logrus, slog writes to stderr
+	// stderr is streamed to the task log line by line, as it is produced.
+	logw := e.Logger
+	if logw == nil {
+		logw = io.Discard
+	}
+	stderr := newLineWriter(logw, conf.MaxLogSize)
+	cmd := exec.CommandContext(ctx, e.Binpath)
+	cmd.Dir = filepath.Dir(e.Binpath)
+
+	// run the process in a throwaway working directory,
+	// so that the process cannot write to the download
+	// cache, which is shared with later executions.
+	var sandboxEnv []string
+	if e.Sandbox != nil {
+		work, err := e.Sandbox.create()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := work.remove(); err != nil {
+				log.WithError(err).Warn("cannot remove working directory")
+			}
+		}()
+		cmd.Dir = work.path
+		sandboxEnv = work.environ()
+	}
+	cmd.Env = environ(req, e.Binpath, e.Environ, sandboxEnv, conf.Envs, e.Secrets, tracing.Environ(ctx))
+
+	// the isolated process runs as root in the user
+	// namespace, which maps to the sandbox user.
+	if e.Sandbox != nil && e.Sandbox.Isolate {
+		if err := e.Sandbox.isolate(cmd, e.Binpath, filepath.Dir(e.Binpath), cmd.Dir, conf.Network); err != nil {
+			return nil, err
+		}
+	} else if e.Sandbox != nil {
+		if err := e.Sandbox.setCredential(cmd); err != nil {
+			return nil, err
+		}
+	}
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	// apply the resource limits to the process.
+	limiter := newLimiter(conf.Limits, log)
+	defer limiter.close()
+	limiter.prepare(cmd)
+
 	log.Debug("Invoking CGI task")
 
 	// Execute the request
-	handler.ServeHTTP(responseRecorder, req)
-	stderrWriter.Close()
+	if err := cmd.Start(); err != nil {
+		// the kernel refuses to create the namespaces with
+		// EPERM or EINVAL.
+		if e.Sandbox != nil && e.Sandbox.Isolate && (errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EINVAL)) {
+			return nil, fmt.Errorf("%w: cannot create namespaces: %s", ErrIsolationUnsupported, err)
+		}
+		return nil, fmt.Errorf("cannot start CGI process: %w", err)
+	}
+	if err := limiter.apply(cmd.Process.Pid); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	code, header, body, readErr := readResponse(newLimitReader(stdout, conf.Limits.maxOutput()))
+	if errors.Is(readErr, errOutputLimit) {
+		cmd.Process.Kill()
+	}
+	if readErr != nil {
+		// drain the output so the process is not blocked
+		// writing to the pipe.
+		io.Copy(io.Discard, stdout)
+	}
+	waitErr := cmd.Wait()
 
-	// capture stderr output
-	var stderrBuf bytes.Buffer
-	_, err = io.Copy(&stderrBuf, stderrPipe)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CGI output: %w", err)
+	// write the trailing partial line, once the process
+	// stderr is closed.
+	stderr.Flush()
+	if err := stderr.Err(); err != nil {
+		log.WithError(err).Warn("cannot write CGI logs to the task log")
+	}
+
+	// return the cancellation cause if the process
+	// was killed because the context was cancelled.
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	if waitErr != nil {
+		log.WithError(waitErr).Debug("CGI process exited with error")
+	}
+	if errors.Is(readErr, errOutputLimit) {
+		return nil, outputLimitError(conf.Limits.maxOutput())
+	}
+	if readErr != nil {
+		log.WithError(readErr).Error("invalid CGI response")
+		code, header, body = http.StatusInternalServerError, http.Header{}, nil
 	}
+	metrics.CGIExec(code, cmd.ProcessState.ExitCode())
+	span.SetAttributes(
+		attribute.Int("cgi.status", code),
+		attribute.Int("cgi.exit_code", cmd.ProcessState.ExitCode()),
+	)
 
-	log.Infof("Captured CGI logs: %s", stderrBuf.String())
-	encodedBody := base64.StdEncoding.EncodeToString(responseRecorder.Body.Bytes())
-	return &task.CGITaskResponse{StatusCode: responseRecorder.Code, Body: encodedBody, Headers: headerToMap(responseRecorder.Header())}, nil
+	encodedBody := base64.StdEncoding.EncodeToString(body)
+	return &task.CGITaskResponse{
+		StatusCode: code,
+		Body:       encodedBody,
+		Headers:    headerToMap(header),
+		Usage:      limiter.usage(cmd.ProcessState),
+	}, nil
 }
 
 func headerToMap(header http.Header) map[string][]string {
@@ -0,0 +1,146 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task/tracing"
+)
+
+// testScript writes an executable shell script to a
+// temporary directory and returns the path.
+func testScript(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping shell script test on windows")
+	}
+	path := filepath.Join(t.TempDir(), "task.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExec(t *testing.T) {
+	path := testScript(t, `
+echo "Status: 201 Created"
+echo "Content-Type: application/json"
+echo "X-Method: $REQUEST_METHOD"
+echo ""
+cat
+`)
+	conf := &Config{Method: "POST", Endpoint: "/"}
+	res, err := newExecer(path, conf).Exec(context.Background(), []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.StatusCode, 201; got != want {
+		t.Errorf("Want status code %d, got %d", want, got)
+	}
+	if got, want := res.Headers["X-Method"], "POST"; len(got) != 1 || got[0] != want {
+		t.Errorf("Want header %s, got %v", want, got)
+	}
+	body, _ := base64.StdEncoding.DecodeString(res.Body)
+	if got, want := string(body), `{"id":1}`; got != want {
+		t.Errorf("Want body %s, got %s", want, got)
+	}
+}
+
+func TestExec_Cancel(t *testing.T) {
+	path := testScript(t, "exec sleep 30\n")
+	errCanceled := errors.New("canceled")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(50*time.Millisecond, func() { cancel(errCanceled) })
+
+	start := time.Now()
+	_, err := newExecer(path, &Config{Method: "POST", Endpoint: "/"}).Exec(ctx, nil)
+	if !errors.Is(err, errCanceled) {
+		t.Errorf("Want cancellation cause, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("Expect CGI process killed on cancellation")
+	}
+}
+
+func TestExec_TraceContext(t *testing.T) {
+	tracing.SetupInMemory()
+
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo "X-Traceparent: $TRACEPARENT"
+echo "X-Http-Traceparent: $HTTP_TRACEPARENT"
+echo ""
+`)
+	ctx, span := tracing.Start(context.Background(), "test")
+	defer span.End()
+
+	res, err := newExecer(path, &Config{Method: "POST", Endpoint: "/"}).Exec(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceID := span.SpanContext().TraceID().String()
+	for _, key := range []string{"X-Traceparent", "X-Http-Traceparent"} {
+		if got := res.Headers[key]; len(got) != 1 || !strings.Contains(got[0], traceID) {
+			t.Errorf("Want %s with trace id %s, got %v", key, traceID, got)
+		}
+	}
+}
+
+// lineRecorder is an io.Writer that sends each write to
+// a channel.
+type lineRecorder chan string
+
+func (r lineRecorder) Write(p []byte) (int, error) {
+	r <- string(p)
+	return len(p), nil
+}
+
+func TestExec_StreamLogs(t *testing.T) {
+	path := testScript(t, `
+echo "first" >&2
+sleep 1
+echo "second" >&2
+echo "Content-Type: text/plain"
+echo ""
+`)
+	lines := make(lineRecorder, 10)
+	execer := newExecer(path, &Config{Method: "POST", Endpoint: "/"})
+	execer.Logger = lines
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := execer.Exec(context.Background(), nil)
+		done <- err
+	}()
+
+	// the first line is written to the task log while
+	// the process is still running.
+	select {
+	case line := <-lines:
+		if !strings.HasSuffix(line, " first\n") {
+			t.Errorf("Want first line, got %q", line)
+		}
+	case <-done:
+		t.Fatalf("Expect first line streamed before the process exits")
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expect first line streamed")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if line := <-lines; !strings.HasSuffix(line, " second\n") {
+		t.Errorf("Want second line, got %q", line)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// defaultPath provides the PATH of the CGI process when
+// the runner PATH is not inherited.
+const defaultPath = "/usr/local/bin:/usr/bin:/bin"
+
+// environ returns the CGI/1.1 environment for the request,
+// based on the net/http/cgi host implementation. Variables
+// later in the list take precedence.
+func environ(req *http.Request, path string, extra ...[]string) []string {
+	env := []string{
+		"SERVER_SOFTWARE=go",
+		"SERVER_PROTOCOL=HTTP/1.1",
+		"SERVER_NAME=" + req.Host,
+		"SERVER_PORT=80",
+		"HTTP_HOST=" + req.Host,
+		"GATEWAY_INTERFACE=CGI/1.1",
+		"REQUEST_METHOD=" + req.Method,
+		"QUERY_STRING=" + req.URL.RawQuery,
+		"REQUEST_URI=" + req.URL.RequestURI(),
+		"PATH_INFO=" + req.URL.Path,
+		"SCRIPT_NAME=",
+		"SCRIPT_FILENAME=" + path,
+	}
+
+	for k, v := range req.Header {
+		k = strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		if k == "PROXY" {
+			continue
+		}
+		env = append(env, "HTTP_"+k+"="+strings.Join(v, ", "))
+	}
+
+	if req.ContentLength > 0 {
+		env = append(env, fmt.Sprintf("CONTENT_LENGTH=%d", req.ContentLength))
+	}
+	if ctype := req.Header.Get("Content-Type"); ctype != "" {
+		env = append(env, "CONTENT_TYPE="+ctype)
+	}
+
+	// the runner PATH is only used if inherited by the
+	// environment policy.
+	env = append(env, "PATH="+defaultPath)
+
+	for _, e := range extra {
+		env = append(env, e...)
+	}
+	return dedupeEnv(env)
+}
+
+// dedupeEnv removes duplicate environment variables,
+// keeping the last occurrence of each variable.
+func dedupeEnv(env []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(env))
+	for i := len(env) - 1; i >= 0; i-- {
+		key, _, _ := strings.Cut(env[i], "=")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, env[i])
+	}
+	// restore the original ordering
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// readResponse reads the CGI response headers and body
+// from the process output.
+func readResponse(r io.Reader) (int, http.Header, []byte, error) {
+	reader := bufio.NewReader(r)
+	tp := textproto.NewReader(reader)
+
+	mime, err := tp.ReadMIMEHeader()
+	if err != nil && !(errors.Is(err, io.EOF) && len(mime) != 0) {
+		return 0, nil, nil, fmt.Errorf("cgi: error reading headers: %w", err)
+	}
+	headers := http.Header(mime)
+
+	code := 0
+	if status := headers.Get("Status"); status != "" {
+		headers.Del("Status")
+		if len(status) < 3 {
+			return 0, nil, nil, fmt.Errorf("cgi: bogus status (short): %q", status)
+		}
+		if code, err = strconv.Atoi(status[0:3]); err != nil {
+			return 0, nil, nil, fmt.Errorf("cgi: bogus status: %q", status)
+		}
+	}
+	if code == 0 && headers.Get("Location") != "" {
+		code = http.StatusFound
+	}
+	if code == 0 && headers.Get("Content-Type") == "" {
+		return 0, nil, nil, errors.New("cgi: missing required Content-Type in headers")
+	}
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("cgi: error reading body: %w", err)
+	}
+	return code, headers, body, nil
+}
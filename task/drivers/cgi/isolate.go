@@ -0,0 +1,54 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// ErrIsolationUnsupported is returned when process
+// isolation is not supported by the platform or kernel.
+var ErrIsolationUnsupported = errors.New("cgi: process isolation is not supported")
+
+// isolated processes are started by re-executing the
+// program with the initArg as argv[0], and the rules in
+// the isolateEnv environment variable. The init process
+// applies the rules and executes the task binary.
+const (
+	initArg    = "go-task-isolate-init"
+	isolateEnv = "GO_TASK_ISOLATE"
+)
+
+// initialized is true if Init was called by the program.
+var initialized bool
+
+// rules provides the rules of an isolated process.
+type rules struct {
+	Binary string   `json:"binary"`
+	Exec   []string `json:"exec"`  // paths with read and execute access
+	Write  []string `json:"write"` // paths with full access
+	Ports  []uint16 `json:"ports"` // tcp ports the process can connect to
+}
+
+// ports returns the tcp ports of the network endpoints,
+// in host:port format.
+func ports(endpoints []string) ([]uint16, error) {
+	var out []uint16
+	for _, endpoint := range endpoints {
+		_, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network endpoint %q: %w", endpoint, err)
+		}
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid network endpoint %q: invalid port", endpoint)
+		}
+		out = append(out, uint16(n))
+	}
+	return out, nil
+}
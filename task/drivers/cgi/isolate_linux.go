@@ -0,0 +1,201 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package cgi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// systemPaths provides the system paths the isolated
+// process can read and execute.
+var systemPaths = []string{
+	"/bin",
+	"/sbin",
+	"/usr",
+	"/lib",
+	"/lib32",
+	"/lib64",
+}
+
+// configPaths provides the system configuration files the
+// isolated process can read. The files are limited to the
+// dynamic linker, locale, user, name resolution and
+// certificate files, so that host secrets in /etc are not
+// readable.
+var configPaths = []string{
+	"/etc/ld.so.cache",
+	"/etc/ld.so.conf",
+	"/etc/ld.so.conf.d",
+	"/etc/localtime",
+	"/etc/nsswitch.conf",
+	"/etc/passwd",
+	"/etc/group",
+	"/etc/hosts",
+	"/etc/resolv.conf",
+	"/etc/ssl/certs",
+	"/etc/pki/tls/certs",
+	"/etc/ca-certificates",
+}
+
+// devicePaths provides the device files the isolated
+// process can read and write.
+var devicePaths = []string{
+	"/dev/null",
+	"/dev/zero",
+	"/dev/random",
+	"/dev/urandom",
+}
+
+// Init runs the init process of an isolated or resource
+// limited CGI process, which applies the sandbox rules or
+// the resource limits and executes the task binary, and
+// does not return. Otherwise Init returns immediately.
+// Programs that enable Sandbox.Isolate must call Init at
+// the start of main, and programs that set Limits should,
+// so that the limits apply before the binary is executed.
+func Init() {
+	if len(os.Args) == 0 {
+		initialized = true
+		return
+	}
+	switch os.Args[0] {
+	case initArg:
+		// the landlock domain and the no_new_privs flag apply
+		// to the thread, which must be the thread that
+		// executes the binary.
+		runtime.LockOSThread()
+		if err := initIsolated(); err != nil {
+			fmt.Fprintf(os.Stderr, "go-task: cannot isolate process: %s\n", err)
+			os.Exit(126)
+		}
+	case limitInitArg:
+		if err := initLimited(); err != nil {
+			fmt.Fprintf(os.Stderr, "go-task: cannot limit process: %s\n", err)
+			os.Exit(126)
+		}
+	default:
+		initialized = true
+	}
+}
+
+// initIsolated applies the rules and executes the binary.
+func initIsolated() error {
+	r := new(rules)
+	if err := json.Unmarshal([]byte(os.Getenv(isolateEnv)), r); err != nil {
+		return err
+	}
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, isolateEnv+"=") {
+			env = append(env, e)
+		}
+	}
+
+	// mount a proc filesystem for the pid namespace, in a
+	// private mount namespace. The host proc filesystem
+	// is not accessible if the mount fails.
+	proc := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "") == nil &&
+		unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "") == nil
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+	if err := restrict(r, proc); err != nil {
+		return err
+	}
+	return syscall.Exec(r.Binary, []string{r.Binary}, env)
+}
+
+// isolate configures the command to run the binary in
+// user, mount, pid and network namespaces, restricted by
+// landlock to the artifact and working directories. The
+// process has network access only if endpoints are
+// declared, and tcp connections are then restricted to
+// the endpoint ports. The process runs as root in the user
+// namespace, which must map to a non-root user.
+func (s *Sandbox) isolate(cmd *exec.Cmd, binary, artifact, workdir string, endpoints []string) error {
+	if !initialized {
+		return fmt.Errorf("%w: the program must call cgi.Init", ErrIsolationUnsupported)
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	if s.UID != 0 {
+		uid = int(s.UID)
+	}
+	if s.GID != 0 {
+		gid = int(s.GID)
+	}
+	if uid == 0 {
+		return errors.New("cgi: isolated processes cannot run as root, set the sandbox uid")
+	}
+	if err := checkUserNamespaces(); err != nil {
+		return err
+	}
+	abi := landlockABI()
+	if abi < 1 {
+		return fmt.Errorf("%w: landlock is not enabled in the kernel, requires linux 5.13 or later", ErrIsolationUnsupported)
+	}
+	ports, err := ports(endpoints)
+	if err != nil {
+		return err
+	}
+	if len(ports) != 0 && abi < 4 {
+		return fmt.Errorf("%w: network endpoints require landlock abi 4, linux 6.7 or later, found abi %d", ErrIsolationUnsupported, abi)
+	}
+	data, err := json.Marshal(&rules{
+		Binary: binary,
+		Exec:   []string{artifact},
+		Write:  []string{workdir},
+		Ports:  ports,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID
+	if len(ports) == 0 {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+
+	// switch to root in the user namespace, since the
+	// credentials of a root runner are not mapped.
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0, NoSetGroups: true}
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{initArg}
+	cmd.Env = append(cmd.Env, isolateEnv+"="+string(data))
+	return nil
+}
+
+// checkUserNamespaces returns an error if the kernel does
+// not allow the runner to create user namespaces.
+func checkUserNamespaces() error {
+	if _, err := os.Stat("/proc/self/ns/user"); err != nil {
+		return fmt.Errorf("%w: user namespaces are not enabled in the kernel", ErrIsolationUnsupported)
+	}
+	if data, err := os.ReadFile("/proc/sys/user/max_user_namespaces"); err == nil && strings.TrimSpace(string(data)) == "0" {
+		return fmt.Errorf("%w: user namespaces are disabled by user.max_user_namespaces", ErrIsolationUnsupported)
+	}
+	if data, err := os.ReadFile("/proc/sys/kernel/unprivileged_userns_clone"); err == nil && strings.TrimSpace(string(data)) == "0" && os.Getuid() != 0 {
+		return fmt.Errorf("%w: unprivileged user namespaces are disabled by kernel.unprivileged_userns_clone", ErrIsolationUnsupported)
+	}
+	return nil
+}
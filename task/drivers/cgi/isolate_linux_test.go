@@ -0,0 +1,140 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package cgi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary is the init process of the
+	// isolated processes.
+	Init()
+	os.Exit(m.Run())
+}
+
+// testIsolation skips the test if the kernel does not
+// support process isolation.
+func testIsolation(t *testing.T) {
+	t.Helper()
+	if err := checkUserNamespaces(); err != nil {
+		t.Skip(err)
+	}
+	if landlockABI() < 1 {
+		t.Skip("landlock is not supported")
+	}
+}
+
+// testSandbox returns an isolated sandbox. A root runner
+// runs the process as nobody, which requires that the
+// binary is accessible to the user.
+func testSandbox(t *testing.T, binary string) *Sandbox {
+	t.Helper()
+	sandbox := &Sandbox{Dir: t.TempDir(), Isolate: true}
+	if os.Getuid() == 0 {
+		sandbox.UID, sandbox.GID = 65534, 65534
+		os.Chmod(filepath.Dir(binary), 0755)
+		os.Chmod(filepath.Dir(filepath.Dir(binary)), 0755)
+	}
+	return sandbox
+}
+
+func TestExec_Isolate(t *testing.T) {
+	testIsolation(t)
+
+	// a file outside of the sandbox.
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(secret, []byte("s3cr3t"), 0644)
+
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo "X-Pid: $$"
+echo "X-User: $(id -u)"
+echo "X-Read-Outside: $(cat `+secret+` 2>/dev/null || echo denied)"
+echo "X-Write-Artifact: $(touch $(dirname $0)/file 2>/dev/null && echo ok || echo denied)"
+echo "X-Write-Workdir: $(touch file && echo ok || echo denied)"
+echo "X-Network: $(cat /proc/net/dev | tail -n +3 | cut -d: -f1 | tr -d " " | tr "\n" ",")"
+echo "X-Read-Config: $(cat /etc/hostname 2>/dev/null || echo denied)"
+echo "X-Read-Passwd: $(cat /etc/passwd >/dev/null 2>&1 && echo ok || echo denied)"
+echo ""
+`)
+	execer := newExecer(path, &Config{Method: "POST", Endpoint: "/"})
+	execer.Sandbox = testSandbox(t, path)
+
+	res, err := execer.Exec(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"X-Pid":            "1",
+		"X-User":           "0",
+		"X-Read-Outside":   "denied",
+		"X-Write-Artifact": "denied",
+		"X-Write-Workdir":  "ok",
+		"X-Network":        "lo,",
+		"X-Read-Config":    "denied",
+		"X-Read-Passwd":    "ok",
+	}
+	for key, value := range want {
+		if got := res.Headers[key]; len(got) != 1 || got[0] != value {
+			t.Errorf("Want %s %q, got %v", key, value, got)
+		}
+	}
+}
+
+func TestExec_IsolateNetwork(t *testing.T) {
+	testIsolation(t)
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo "X-Network: $(cat /proc/net/dev | tail -n +3 | cut -d: -f1 | tr -d " " | grep -c .)"
+echo ""
+`)
+	execer := newExecer(path, &Config{Method: "POST", Endpoint: "/", Network: []string{"example.com:443"}})
+	execer.Sandbox = testSandbox(t, path)
+
+	res, err := execer.Exec(context.Background(), nil)
+	if landlockABI() < 4 {
+		if !errors.Is(err, ErrIsolationUnsupported) {
+			t.Errorf("Want isolation unsupported error, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the process shares the host network namespace.
+	if got := res.Headers["X-Network"]; len(got) != 1 || got[0] == "1" {
+		t.Errorf("Want host network interfaces, got %v", got)
+	}
+}
+
+func TestExec_IsolateRoot(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("the runner is not root")
+	}
+	execer := newExecer("/bin/true", &Config{Method: "POST", Endpoint: "/"})
+	execer.Sandbox = &Sandbox{Dir: t.TempDir(), Isolate: true}
+	if _, err := execer.Exec(context.Background(), nil); err == nil {
+		t.Errorf("Want error isolating a process as root")
+	}
+}
+
+func TestExec_IsolateNotInitialized(t *testing.T) {
+	defer func() { initialized = true }()
+	initialized = false
+
+	execer := newExecer("/bin/true", &Config{Method: "POST", Endpoint: "/"})
+	execer.Sandbox = &Sandbox{Dir: t.TempDir(), Isolate: true}
+	_, err := execer.Exec(context.Background(), nil)
+	if !errors.Is(err, ErrIsolationUnsupported) {
+		t.Errorf("Want isolation unsupported error, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package cgi
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// Init runs the init process of an isolated CGI process.
+// Process isolation is not supported on this platform,
+// and Init returns immediately.
+func Init() {
+	initialized = true
+}
+
+// isolate returns an error, since process isolation is
+// not supported on this platform.
+func (s *Sandbox) isolate(cmd *exec.Cmd, binary, artifact, workdir string, endpoints []string) error {
+	return fmt.Errorf("%w: requires linux, found %s", ErrIsolationUnsupported, runtime.GOOS)
+}
@@ -0,0 +1,25 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPorts(t *testing.T) {
+	got, err := ports([]string{"example.com:443", "10.0.0.1:8080", "[::1]:22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{443, 8080, 22}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Want ports %v, got %v", want, got)
+	}
+	for _, endpoint := range []string{"example.com", "example.com:http", "example.com:0", "example.com:65536"} {
+		if _, err := ports([]string{endpoint}); err == nil {
+			t.Errorf("Want error for endpoint %s", endpoint)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Limits provides the resource limits of the CGI process.
+// The limits are applied using cgroup v2 when available,
+// and setrlimit otherwise, in which case the cpu and pids
+// limits are not enforced.
+type Limits struct {
+	// Memory provides the memory limit in bytes.
+	Memory int64 `json:"memory"`
+
+	// CPU provides the cpu quota in cores, for example
+	// 0.5 limits the process to half a core.
+	CPU float64 `json:"cpu"`
+
+	// Pids provides the limit of processes and threads.
+	Pids int64 `json:"pids"`
+
+	// OpenFiles provides the limit of open files.
+	OpenFiles uint64 `json:"open_files"`
+
+	// MaxOutput provides the limit, in bytes, of the
+	// process output. The process is killed when the
+	// limit is exceeded.
+	MaxOutput int64 `json:"max_output"`
+}
+
+// validate returns an error if a limit is negative.
+func (l *Limits) validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.Memory < 0 || l.CPU < 0 || l.Pids < 0 || l.MaxOutput < 0 {
+		return errors.New("resource limits must not be negative")
+	}
+	return nil
+}
+
+// maxOutput returns the output limit, or zero if not set.
+func (l *Limits) maxOutput() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.MaxOutput
+}
+
+// errOutputLimit is returned when the process output
+// exceeds the limit.
+var errOutputLimit = errors.New("output limit exceeded")
+
+// limitReader is an io.Reader that returns errOutputLimit
+// once more than n bytes are read.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+// newLimitReader returns a reader that reads from r, up
+// to limit bytes. The reader is returned unchanged if
+// the limit is not set.
+func newLimitReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitReader{r: r, n: limit}
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errOutputLimit
+	}
+	// read one byte past the limit to detect output
+	// that exceeds the limit.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errOutputLimit
+	}
+	return n, err
+}
+
+// outputLimitError returns the error for output that
+// exceeds the limit.
+func outputLimitError(limit int64) error {
+	return fmt.Errorf("cgi: process output exceeds the limit of %d bytes: %w", limit, errOutputLimit)
+}
@@ -0,0 +1,378 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package cgi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/drone/go-task/task"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// cgroupRoot is the cgroup v2 mount point, and can be
+// replaced in unit tests.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cpuPeriod is the cgroup cpu period in microseconds.
+const cpuPeriod = 100000
+
+// limited processes are started by re-executing the
+// program with the limitInitArg as argv[0], followed by the
+// path and arguments of the command, and the rlimits in the
+// limitEnv environment variable. The init process applies
+// the rlimits and executes the command, so that the limits
+// apply before the command is executed.
+const (
+	limitInitArg = "go-task-limit-init"
+	limitEnv     = "GO_TASK_LIMIT"
+)
+
+// rlimit is a resource limit applied with setrlimit.
+type rlimit struct {
+	Resource int    `json:"resource"`
+	Value    uint64 `json:"value"`
+}
+
+// limiter applies the resource limits to the process,
+// using a cgroup v2 child of the runner cgroup when
+// available, and setrlimit otherwise.
+type limiter struct {
+	limits  *Limits
+	log     *logrus.Entry
+	cgroup  string   // path of the process cgroup
+	fd      *os.File // open cgroup directory
+	rlimits []rlimit // rlimits applied after the process is started
+}
+
+// newLimiter returns a limiter for the limits.
+func newLimiter(limits *Limits, log *logrus.Entry) *limiter {
+	return &limiter{limits: limits, log: log}
+}
+
+// prepare prepares the command, before the process is
+// started, to start the process in the cgroup, and to
+// apply the rlimits before the command is executed.
+func (l *limiter) prepare(cmd *exec.Cmd) {
+	if l.limits == nil {
+		return
+	}
+	if l.limits.Memory != 0 || l.limits.CPU != 0 || l.limits.Pids != 0 {
+		l.prepareCgroup(cmd)
+	}
+
+	var rlimits []rlimit
+	if n := l.limits.OpenFiles; n > 0 {
+		rlimits = append(rlimits, rlimit{Resource: unix.RLIMIT_NOFILE, Value: n})
+	}
+	// the data segment limit, unlike the address space
+	// limit, does not count the address space reserved
+	// but not used by the Go runtime.
+	if n := l.limits.Memory; n > 0 && l.cgroup == "" {
+		rlimits = append(rlimits, rlimit{Resource: unix.RLIMIT_DATA, Value: uint64(n)})
+	}
+	if len(rlimits) == 0 {
+		return
+	}
+
+	// the rlimits are applied with prlimit once the process
+	// is started if the program does not call Init, which
+	// leaves a window in which the limits are not applied.
+	data, err := json.Marshal(rlimits)
+	if !initialized || err != nil {
+		l.log.Warn("the program does not call cgi.Init, resource limits are applied after the process is started")
+		l.rlimits = rlimits
+		return
+	}
+	cmd.Args = append([]string{limitInitArg, cmd.Path}, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+	cmd.Env = append(cmd.Env, limitEnv+"="+string(data))
+}
+
+// prepareCgroup prepares the command to start the process
+// in a new cgroup with the limits.
+func (l *limiter) prepareCgroup(cmd *exec.Cmd) {
+	path, err := createCgroup(l.limits)
+	if err == nil {
+		l.fd, err = os.Open(path)
+		if err != nil {
+			os.Remove(path)
+		}
+	}
+	if err != nil {
+		l.log.WithError(err).Warn("cgroup v2 is not available, falling back to setrlimit")
+		if l.limits.CPU != 0 || l.limits.Pids != 0 {
+			l.log.Warn("cpu and pids limits require cgroup v2 and are not enforced")
+		}
+		return
+	}
+	l.cgroup = path
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(l.fd.Fd())
+}
+
+// apply applies the rlimits that were not applied before
+// the command was executed to the started process.
+func (l *limiter) apply(pid int) error {
+	for _, r := range l.rlimits {
+		if err := unix.Prlimit(pid, r.Resource, &unix.Rlimit{Cur: r.Value, Max: r.Value}, nil); err != nil {
+			return fmt.Errorf("cannot apply resource limit: %w", err)
+		}
+	}
+	return nil
+}
+
+// initLimited applies the rlimits and executes the command.
+func initLimited() error {
+	var rlimits []rlimit
+	if err := json.Unmarshal([]byte(os.Getenv(limitEnv)), &rlimits); err != nil {
+		return err
+	}
+	if len(os.Args) < 3 {
+		return errors.New("missing command")
+	}
+	var env []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, limitEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	// syscall.Setrlimit is used, since the Go runtime
+	// otherwise restores the open files limit on exec.
+	for _, r := range rlimits {
+		if err := syscall.Setrlimit(r.Resource, &syscall.Rlimit{Cur: r.Value, Max: r.Value}); err != nil {
+			return err
+		}
+	}
+	return syscall.Exec(os.Args[1], os.Args[2:], env)
+}
+
+// usage returns the resource usage of the exited process.
+func (l *limiter) usage(state *os.ProcessState) *task.Usage {
+	usage := &task.Usage{
+		UserTime: state.UserTime(),
+		SysTime:  state.SystemTime(),
+	}
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		usage.PeakMemory = rusage.Maxrss * 1024 // kilobytes
+	}
+	if l.cgroup == "" {
+		return usage
+	}
+	// the cgroup usage includes the usage of processes
+	// that were not waited for by the process.
+	if peak, err := readCgroupInt(l.cgroup, "memory.peak"); err == nil {
+		usage.PeakMemory = peak
+	}
+	if stat, err := readCgroupStat(l.cgroup, "cpu.stat"); err == nil {
+		usage.UserTime = time.Duration(stat["user_usec"]) * time.Microsecond
+		usage.SysTime = time.Duration(stat["system_usec"]) * time.Microsecond
+	}
+	return usage
+}
+
+// close kills the processes remaining in the process
+// cgroup, such as daemonized descendants, and removes the
+// cgroup.
+func (l *limiter) close() {
+	if l.fd != nil {
+		l.fd.Close()
+	}
+	if l.cgroup == "" {
+		return
+	}
+	if err := killCgroup(l.cgroup); err != nil {
+		l.log.WithError(err).Warn("cannot kill cgroup processes")
+	}
+	// the cgroup cannot be removed until the killed
+	// processes have exited.
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = os.Remove(l.cgroup); !errors.Is(err, unix.EBUSY) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		l.log.WithError(err).Warn("cannot remove cgroup")
+	}
+}
+
+// killCgroup kills the processes in the cgroup, using
+// cgroup.kill if supported by the kernel.
+func killCgroup(dir string) error {
+	f, err := os.OpenFile(filepath.Join(dir, "cgroup.kill"), os.O_WRONLY, 0)
+	if err == nil {
+		_, err = f.WriteString("1")
+		f.Close()
+		return err
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			unix.Kill(pid, unix.SIGKILL)
+		}
+	}
+	return nil
+}
+
+// runnerCgroup is the name of the leaf cgroup in which the
+// runner can be started. The task cgroups are then created
+// in the parent cgroup, since under the cgroup v2 no
+// internal processes rule the controllers cannot be
+// enabled for the task cgroups in a cgroup that has
+// processes.
+const runnerCgroup = "go-task-runner"
+
+// createCgroup creates a cgroup for the process, as a
+// child of the runner cgroup, and writes the limits.
+func createCgroup(limits *Limits) (string, error) {
+	parent, err := parentCgroup()
+	if err != nil {
+		return "", err
+	}
+	path, err := os.MkdirTemp(parent, "go-task-")
+	if err != nil {
+		return "", err
+	}
+	files := map[string]string{}
+	if limits.Memory > 0 {
+		files["memory.max"] = strconv.FormatInt(limits.Memory, 10)
+		files["memory.swap.max"] = "0"
+	}
+	if limits.CPU > 0 {
+		quota := max(int64(limits.CPU*cpuPeriod), 1000)
+		files["cpu.max"] = fmt.Sprintf("%d %d", quota, cpuPeriod)
+	}
+	if limits.Pids > 0 {
+		files["pids.max"] = strconv.FormatInt(limits.Pids, 10)
+	}
+	for name, value := range files {
+		err := os.WriteFile(filepath.Join(path, name), []byte(value), 0)
+		// swap accounting is optional.
+		if err != nil && name != "memory.swap.max" {
+			os.Remove(path)
+			return "", fmt.Errorf("cannot write %s: %w", name, err)
+		}
+	}
+	return path, nil
+}
+
+// parentCgroup returns the cgroup in which the task cgroups
+// are created, which is the runner cgroup, or the parent of
+// the runnerCgroup leaf. The cgroup must be delegated to
+// the runner, and the controllers are enabled for the task
+// cgroups. The processes of the cgroup are never moved, so
+// a cgroup hierarchy the runner does not own is unchanged.
+func parentCgroup() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 is not mounted")
+	}
+	self, err := selfCgroup()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(cgroupRoot, self)
+	if filepath.Base(parent) == runnerCgroup {
+		parent = filepath.Dir(parent)
+	}
+	if err := unix.Access(parent, unix.W_OK); err != nil {
+		return "", fmt.Errorf("cgroup %s is not delegated to the runner: %w", parent, err)
+	}
+	if err := enableControllers(parent); err != nil {
+		return "", fmt.Errorf("cannot enable controllers in cgroup %s, the runner must be started in a %s leaf of a delegated cgroup: %w", parent, runnerCgroup, err)
+	}
+	return parent, nil
+}
+
+// enableControllers enables the memory, cpu and pids
+// controllers for the child cgroups, unless enabled.
+func enableControllers(dir string) error {
+	path := filepath.Join(dir, "cgroup.subtree_control")
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var missing []string
+	enabled := strings.Fields(string(data))
+	for _, name := range []string{"memory", "cpu", "pids"} {
+		if !slices.Contains(enabled, name) {
+			missing = append(missing, "+"+name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return os.WriteFile(path, []byte(strings.Join(missing, " ")), 0)
+}
+
+// selfCgroup returns the cgroup v2 path of the runner.
+func selfCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("cgroup v2 path not found")
+}
+
+// readCgroupInt reads an integer cgroup file.
+func readCgroupInt(dir, name string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 64)
+}
+
+// readCgroupStat reads a flat keyed cgroup file.
+func readCgroupStat(dir, name string) (map[string]int64, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat := map[string]int64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			stat[key] = n
+		}
+	}
+	return stat, scanner.Err()
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package cgi
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExec_Rlimits(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = t.TempDir() // cgroup v2 is not available
+
+	// the limits are applied before the script is executed.
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo "X-Open-Files: $(ulimit -n)"
+echo "X-Data: $(ulimit -d)"
+echo ""
+`)
+	conf := &Config{Method: "POST", Endpoint: "/", Limits: &Limits{OpenFiles: 64, Memory: 512 * 1024 * 1024}}
+	res, err := newExecer(path, conf).Exec(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Headers["X-Open-Files"]; len(got) != 1 || got[0] != "64" {
+		t.Errorf("Want open files limit 64, got %v", got)
+	}
+	if got := res.Headers["X-Data"]; len(got) != 1 || got[0] != "524288" {
+		t.Errorf("Want data limit 524288 kilobytes, got %v", got)
+	}
+	if res.Usage.PeakMemory == 0 {
+		t.Errorf("Expect peak memory reported")
+	}
+}
+
+func TestExec_RlimitsNotInitialized(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = t.TempDir() // cgroup v2 is not available
+	defer func() { initialized = true }()
+	initialized = false
+
+	// the limits are applied once the process is started,
+	// so the script waits before reading the limits.
+	path := testScript(t, `
+sleep 0.5
+echo "Content-Type: text/plain"
+echo "X-Open-Files: $(ulimit -n)"
+echo ""
+`)
+	conf := &Config{Method: "POST", Endpoint: "/", Limits: &Limits{OpenFiles: 64}}
+	res, err := newExecer(path, conf).Exec(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Headers["X-Open-Files"]; len(got) != 1 || got[0] != "64" {
+		t.Errorf("Want open files limit 64, got %v", got)
+	}
+}
+
+func TestCreateCgroup(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = t.TempDir()
+	os.WriteFile(filepath.Join(cgroupRoot, "cgroup.controllers"), []byte("cpu memory pids"), 0644)
+
+	self, err := selfCgroup()
+	if err != nil {
+		t.Skip(err)
+	}
+	os.MkdirAll(filepath.Join(cgroupRoot, self), 0755)
+
+	path, err := createCgroup(&Limits{Memory: 1024 * 1024, CPU: 0.5, Pids: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"memory.max": "1048576",
+		"cpu.max":    "50000 100000",
+		"pids.max":   "10",
+	}
+	for name, value := range want {
+		data, _ := os.ReadFile(filepath.Join(path, name))
+		if got := string(data); got != value {
+			t.Errorf("Want %s %q, got %q", name, value, got)
+		}
+	}
+
+	os.WriteFile(filepath.Join(path, "memory.peak"), []byte("2048\n"), 0644)
+	os.WriteFile(filepath.Join(path, "cpu.stat"), []byte("usage_usec 3000\nuser_usec 2000\nsystem_usec 1000\n"), 0644)
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip(err)
+	}
+	l := &limiter{cgroup: path}
+	usage := l.usage(cmd.ProcessState)
+	if usage.PeakMemory != 2048 || usage.UserTime != 2*time.Millisecond || usage.SysTime != time.Millisecond {
+		t.Errorf("Want cgroup usage, got %+v", usage)
+	}
+	if !strings.HasPrefix(filepath.Base(path), "go-task-") {
+		t.Errorf("Want cgroup name with prefix go-task-, got %s", path)
+	}
+}
+
+func TestEnableControllers(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("cpu memory\n"), 0644)
+	if err := enableControllers(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	if got, want := string(data), "+pids"; got != want {
+		t.Errorf("Want missing controllers %q enabled, got %q", want, got)
+	}
+}
+
+func TestKillCgroup(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	// cgroup.kill is not supported, so the processes in
+	// cgroup.procs are killed.
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644)
+	if err := killCgroup(dir); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		t.Errorf("Want cgroup processes killed")
+	}
+}
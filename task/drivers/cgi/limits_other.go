@@ -0,0 +1,54 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package cgi
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/drone/go-task/task"
+
+	"github.com/sirupsen/logrus"
+)
+
+// limiter reports the resource usage of the process.
+// Resource limits, other than the output limit, are not
+// supported on this platform.
+type limiter struct {
+	limits *Limits
+	log    *logrus.Entry
+}
+
+// newLimiter returns a limiter for the limits.
+func newLimiter(limits *Limits, log *logrus.Entry) *limiter {
+	return &limiter{limits: limits, log: log}
+}
+
+// prepare prepares the command before the process is
+// started.
+func (l *limiter) prepare(cmd *exec.Cmd) {
+	if l.limits != nil && (l.limits.Memory != 0 || l.limits.CPU != 0 || l.limits.Pids != 0 || l.limits.OpenFiles != 0) {
+		l.log.Warnf("resource limits are not supported on %s and are not enforced", runtime.GOOS)
+	}
+}
+
+// apply applies the limits to the started process.
+func (l *limiter) apply(pid int) error {
+	return nil
+}
+
+// usage returns the resource usage of the exited process.
+func (l *limiter) usage(state *os.ProcessState) *task.Usage {
+	return &task.Usage{
+		UserTime: state.UserTime(),
+		SysTime:  state.SystemTime(),
+	}
+}
+
+// close releases the limiter resources.
+func (l *limiter) close() {}
@@ -0,0 +1,51 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimitReader(t *testing.T) {
+	b, err := io.ReadAll(newLimitReader(strings.NewReader("hello"), 5))
+	if err != nil || string(b) != "hello" {
+		t.Errorf("Want output within the limit, got %q, %v", b, err)
+	}
+	_, err = io.ReadAll(newLimitReader(strings.NewReader("hello world"), 5))
+	if !errors.Is(err, errOutputLimit) {
+		t.Errorf("Want output limit error, got %v", err)
+	}
+}
+
+func TestExec_MaxOutput(t *testing.T) {
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo ""
+exec yes
+`)
+	conf := &Config{Method: "POST", Endpoint: "/", Limits: &Limits{MaxOutput: 1024}}
+	_, err := newExecer(path, conf).Exec(context.Background(), nil)
+	if !errors.Is(err, errOutputLimit) {
+		t.Errorf("Want output limit error, got %v", err)
+	}
+}
+
+func TestExec_Usage(t *testing.T) {
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo ""
+`)
+	res, err := newExecer(path, &Config{Method: "POST", Endpoint: "/"}).Exec(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Usage == nil {
+		t.Fatalf("Expect resource usage reported")
+	}
+}
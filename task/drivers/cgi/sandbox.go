@@ -0,0 +1,102 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Sandbox provides the per-execution working directory
+// and user of the CGI process. The working directory is
+// removed once the process exits.
+type Sandbox struct {
+	// Dir provides the directory in which the working
+	// directories are created. Defaults to os.TempDir.
+	Dir string
+
+	// UID and GID provide the user and group of the
+	// process. Zero runs the process as the runner user
+	// and group. Switching user requires privileges, and
+	// the binary must be accessible to the user.
+	UID uint32
+	GID uint32
+
+	// Isolate runs the process in user, mount, pid and
+	// network namespaces, restricted by landlock to the
+	// system directories, the artifact directory and the
+	// working directory. The process runs as the sandbox
+	// user, which must not be root. Requires linux 5.13 or
+	// later with landlock enabled, and that the program
+	// calls Init.
+	Isolate bool
+}
+
+// workdir is the working directory of an execution.
+type workdir struct {
+	path string
+}
+
+// create creates the working directory, including the
+// tmp directory, owned by the sandbox user.
+func (s *Sandbox) create() (*workdir, error) {
+	path, err := os.MkdirTemp(s.Dir, "go-task-")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create working directory: %w", err)
+	}
+	w := &workdir{path: path}
+	if err := os.Mkdir(w.tmp(), 0700); err != nil {
+		w.remove()
+		return nil, fmt.Errorf("cannot create working directory: %w", err)
+	}
+	if s.UID != 0 || s.GID != 0 {
+		for _, dir := range []string{w.path, w.tmp()} {
+			if err := os.Chown(dir, s.uid(), s.gid()); err != nil {
+				w.remove()
+				return nil, fmt.Errorf("cannot change working directory owner: %w", err)
+			}
+		}
+	}
+	return w, nil
+}
+
+// uid returns the user id, or -1 to keep the owner.
+func (s *Sandbox) uid() int {
+	if s.UID == 0 {
+		return -1
+	}
+	return int(s.UID)
+}
+
+// gid returns the group id, or -1 to keep the group.
+func (s *Sandbox) gid() int {
+	if s.GID == 0 {
+		return -1
+	}
+	return int(s.GID)
+}
+
+// tmp returns the temporary directory of the execution.
+func (w *workdir) tmp() string {
+	return filepath.Join(w.path, "tmp")
+}
+
+// environ returns the environment variables that point
+// the home and temporary directories at the working
+// directory.
+func (w *workdir) environ() []string {
+	return []string{
+		"HOME=" + w.path,
+		"TMPDIR=" + w.tmp(),
+		"TMP=" + w.tmp(),
+		"TEMP=" + w.tmp(),
+	}
+}
+
+// remove removes the working directory.
+func (w *workdir) remove() error {
+	return os.RemoveAll(w.path)
+}
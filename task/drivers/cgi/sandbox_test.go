@@ -0,0 +1,49 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExec_Sandbox(t *testing.T) {
+	path := testScript(t, `
+touch output.txt
+echo "Content-Type: text/plain"
+echo "X-Pwd: $(pwd)"
+echo "X-Home: $HOME"
+echo "X-Tmpdir: $TMPDIR"
+echo ""
+`)
+	dir := t.TempDir()
+	execer := newExecer(path, &Config{Method: "POST", Endpoint: "/"})
+	execer.Sandbox = &Sandbox{Dir: dir}
+
+	res, err := execer.Exec(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pwd := res.Headers["X-Pwd"]
+	if len(pwd) != 1 || !strings.HasPrefix(pwd[0], filepath.Join(dir, "go-task-")) {
+		t.Fatalf("Want working directory in %s, got %v", dir, pwd)
+	}
+	if got := res.Headers["X-Home"]; len(got) != 1 || got[0] != pwd[0] {
+		t.Errorf("Want HOME %s, got %v", pwd[0], got)
+	}
+	if got, want := res.Headers["X-Tmpdir"], filepath.Join(pwd[0], "tmp"); len(got) != 1 || got[0] != want {
+		t.Errorf("Want TMPDIR %s, got %v", want, got)
+	}
+	if _, err := os.Stat(pwd[0]); !os.IsNotExist(err) {
+		t.Errorf("Expect working directory removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "output.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expect binary directory not written, got %v", err)
+	}
+}
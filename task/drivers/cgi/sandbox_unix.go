@@ -0,0 +1,33 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package cgi
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCredential configures the command to run the
+// process as the sandbox user and group.
+func (s *Sandbox) setCredential(cmd *exec.Cmd) error {
+	if s.UID == 0 && s.GID == 0 {
+		return nil
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	uid, gid := uint32(syscall.Getuid()), uint32(syscall.Getgid())
+	if s.UID != 0 {
+		uid = s.UID
+	}
+	if s.GID != 0 {
+		gid = s.GID
+	}
+	// the supplementary groups of the runner are dropped.
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package cgi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExec_SandboxUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("switching user requires root")
+	}
+	path := testScript(t, `
+echo "Content-Type: text/plain"
+echo "X-User: $(id -u):$(id -g)"
+echo "X-Write: $(touch file && echo ok)"
+echo ""
+`)
+	// the binary must be accessible to the user.
+	os.Chmod(filepath.Dir(path), 0755)
+	os.Chmod(filepath.Dir(filepath.Dir(path)), 0755)
+
+	execer := newExecer(path, &Config{Method: "POST", Endpoint: "/"})
+	execer.Sandbox = &Sandbox{Dir: t.TempDir(), UID: 65534, GID: 65534}
+
+	res, err := execer.Exec(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Headers["X-User"]; len(got) != 1 || got[0] != "65534:65534" {
+		t.Errorf("Want user 65534:65534, got %v", got)
+	}
+	if got := res.Headers["X-Write"]; len(got) != 1 || got[0] != "ok" {
+		t.Errorf("Expect working directory writable by the user, got %v", got)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+
+package cgi
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setCredential configures the command to run the
+// process as the sandbox user and group.
+func (s *Sandbox) setCredential(cmd *exec.Cmd) error {
+	if s.UID == 0 && s.GID == 0 {
+		return nil
+	}
+	return errors.New("switching user is not supported on windows")
+}
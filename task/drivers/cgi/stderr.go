@@ -0,0 +1,98 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// defaultMaxLogSize is the default limit, in bytes, of the
+// CGI process stderr written to the task log.
+const defaultMaxLogSize = 5 * 1024 * 1024
+
+// maxLineSize is the size at which a partial line, without
+// a trailing newline, is written to the task log.
+const maxLineSize = 64 * 1024
+
+// now returns the current time, and can be replaced
+// in unit tests.
+var now = time.Now
+
+// lineWriter is an io.Writer that writes the CGI process
+// stderr to the task log line by line, as it is produced,
+// prefixing each line with a timestamp. Output is discarded
+// once the limit is reached.
+type lineWriter struct {
+	w       io.Writer
+	limit   int
+	written int
+	partial []byte
+	err     error
+}
+
+// newLineWriter returns a lineWriter that writes to w,
+// up to limit bytes.
+func newLineWriter(w io.Writer, limit int) *lineWriter {
+	if limit <= 0 {
+		limit = defaultMaxLogSize
+	}
+	return &lineWriter{w: w, limit: limit}
+}
+
+// Write writes the complete lines in p to the task log,
+// and buffers the trailing partial line.
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.line(l.partial[:i+1])
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.partial) >= maxLineSize {
+		l.Flush()
+	}
+	// the process output is always consumed, even if the
+	// task log cannot be written, so that the process is
+	// not blocked writing to stderr.
+	return len(p), nil
+}
+
+// Flush writes the buffered partial line to the task log.
+func (l *lineWriter) Flush() {
+	if len(l.partial) != 0 {
+		l.line(append(l.partial, '\n'))
+		l.partial = nil
+	}
+}
+
+// Err returns the first error writing to the task log.
+func (l *lineWriter) Err() error {
+	return l.err
+}
+
+// line writes the line to the task log, unless the limit
+// was reached.
+func (l *lineWriter) line(b []byte) {
+	if l.written >= l.limit || l.err != nil {
+		return
+	}
+	line := append([]byte(now().UTC().Format(time.RFC3339Nano)+" "), b...)
+	if l.written+len(line) > l.limit {
+		line = []byte(fmt.Sprintf("%s log output truncated after %d bytes\n",
+			now().UTC().Format(time.RFC3339Nano), l.written))
+		l.written = l.limit
+	} else {
+		l.written += len(line)
+	}
+	if _, err := l.w.Write(line); err != nil {
+		l.err = err
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLineWriter(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	buf := new(bytes.Buffer)
+	w := newLineWriter(buf, 0)
+	w.Write([]byte("hello "))
+	if buf.Len() != 0 {
+		t.Errorf("Expect partial line buffered, got %q", buf.String())
+	}
+	w.Write([]byte("world\nfoo\nbar"))
+	w.Flush()
+
+	want := "2024-01-02T03:04:05Z hello world\n" +
+		"2024-01-02T03:04:05Z foo\n" +
+		"2024-01-02T03:04:05Z bar\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Want lines %q, got %q", want, got)
+	}
+}
+
+func TestLineWriter_Limit(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	buf := new(bytes.Buffer)
+	w := newLineWriter(buf, 40)
+	n, err := w.Write([]byte("first line\nsecond line\nthird line\n"))
+	if err != nil || n != 34 {
+		t.Errorf("Expect output consumed past the limit, got %d, %v", n, err)
+	}
+
+	want := "2024-01-02T03:04:05Z first line\n" +
+		"2024-01-02T03:04:05Z log output truncated after 32 bytes\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Want lines %q, got %q", want, got)
+	}
+}
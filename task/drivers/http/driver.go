@@ -2,4 +2,120 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Package http implements a task execution driver that
+// sends the task data to a remote http endpoint.
 package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+)
+
+// Config provides the driver config.
+type Config struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+
+	// Timeout provides the request timeout in seconds.
+	Timeout int `json:"timeout"`
+}
+
+// New returns the task execution driver. If the client
+// is nil, the default http client is used.
+func New(client *http.Client) task.Handler {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &driver{client: client}
+}
+
+type driver struct {
+	client *http.Client
+}
+
+// Handle handles the task execution request.
+func (d *driver) Handle(ctx context.Context, req *task.Request) task.Response {
+	conf := new(Config)
+	// decode the task configuration
+	if err := json.Unmarshal(req.Task.Config, conf); err != nil {
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "invalid driver configuration", err))
+	}
+	if conf.URL == "" {
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "no url provided", errors.New("no url provided")))
+	}
+	if conf.Method == "" {
+		conf.Method = http.MethodPost
+	}
+	if conf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = task.WithTimeout(ctx, time.Duration(conf.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	log := logger.FromContext(ctx).WithFields(map[string]interface{}{
+		"http.method": conf.Method,
+		"http.url":    conf.URL,
+	})
+
+	// prepare the HTTP request for the endpoint
+	r, err := http.NewRequestWithContext(ctx, conf.Method, conf.URL, bytes.NewReader(req.Task.Data))
+	if err != nil {
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseExec, "invalid http request",
+			fmt.Errorf("cannot create http request: %w", err)))
+	}
+	r.Header.Set("Content-Type", "application/json")
+	for key, value := range conf.Headers {
+		r.Header.Set(key, value)
+	}
+
+	log.Debug("invoking http task")
+
+	res, err := d.client.Do(r)
+	if err != nil {
+		log.WithError(err).Error("could not execute http task")
+		if timeoutErr := task.CheckTimeout(ctx, task.PhaseExec); timeoutErr != nil {
+			return task.Error(timeoutErr)
+		}
+		return task.Error(task.Fail(task.CodeExec, task.PhaseExec, "failed to send http request", err))
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return task.Error(task.Fail(task.CodeExec, task.PhaseExec, "failed to read http response",
+			fmt.Errorf("failed to read http response: %w", err)))
+	}
+
+	// the response uses the same envelope as the cgi
+	// driver so that callers can decode the response
+	// without knowing which driver executed the task.
+	return task.Respond(&task.CGITaskResponse{
+		StatusCode: res.StatusCode,
+		Headers:    res.Header,
+		Body:       base64.StdEncoding.EncodeToString(body),
+	})
+}
+
+// Describe returns the driver metadata.
+func (d *driver) Describe() *task.Metadata {
+	return &task.Metadata{
+		Description: "Sends the task data to a remote http endpoint.",
+	}
+}
+
+// Decode decodes the task output from the CGITaskResponse
+// envelope returned by the driver.
+func (d *driver) Decode(res task.Response) ([]byte, error) {
+	return task.DecodeCGIResponse(res)
+}
@@ -3,3 +3,75 @@
 // license that can be found in the LICENSE file.
 
 package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/go-task/task"
+)
+
+func TestHandle(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Method, http.MethodPut; got != want {
+			t.Errorf("Want method %s, got %s", want, got)
+		}
+		if got, want := r.Header.Get("X-Token"), "secret"; got != want {
+			t.Errorf("Want header %s, got %s", want, got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	config, _ := json.Marshal(&Config{
+		Method:  http.MethodPut,
+		URL:     ts.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	})
+	req := &task.Request{
+		Task: &task.Task{
+			Type:   "http",
+			Driver: "http",
+			Data:   []byte(`{"name":"octocat"}`),
+			Config: config,
+		},
+	}
+
+	res := New(nil).Handle(context.Background(), req)
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(task.CGITaskResponse)
+	if err := json.Unmarshal(res.Body(), out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.StatusCode, http.StatusCreated; got != want {
+		t.Errorf("Want status code %d, got %d", want, got)
+	}
+	body, _ := base64.StdEncoding.DecodeString(out.Body)
+	if got, want := string(body), `{"name":"octocat"}`; got != want {
+		t.Errorf("Want body %s, got %s", want, got)
+	}
+}
+
+func TestHandle_NoURL(t *testing.T) {
+	req := &task.Request{
+		Task: &task.Task{
+			Driver: "http",
+			Config: []byte(`{}`),
+		},
+	}
+	res := New(nil).Handle(context.Background(), req)
+	if res.Error() == nil {
+		t.Errorf("Expect error when no url is provided")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"errors"
+)
+
+// Code identifies the kind of task failure.
+type Code string
+
+const (
+	CodeUnknown    = Code("unknown")
+	CodeNotFound   = Code("not_found")
+	CodeInvalid    = Code("invalid")
+	CodeSecret     = Code("secret_failed")
+	CodeDownload   = Code("download_failed")
+	CodeBuild      = Code("build_failed")
+	CodeExec       = Code("exec_failed")
+	CodeTaskFailed = Code("task_failed")
+	CodeTimeout    = Code("timeout")
+	CodeCanceled   = Code("canceled")
+)
+
+// Failure is a structured task error. The message is safe
+// to display to the user, and does not include the details
+// of the underlying error, which may contain sensitive data.
+type Failure struct {
+	// Code identifies the kind of failure.
+	Code Code `json:"code"`
+
+	// Phase identifies the phase of the task execution
+	// pipeline that failed.
+	Phase Phase `json:"phase,omitempty"`
+
+	// Retryable reports whether the failure is transient
+	// and the task can be retried.
+	Retryable bool `json:"retryable"`
+
+	// Message provides a safe user message.
+	Message string `json:"message"`
+
+	// Err provides the underlying error.
+	Err error `json:"-"`
+}
+
+// Error returns the underlying error message, or the user
+// message if there is no underlying error.
+func (f *Failure) Error() string {
+	if f.Err != nil {
+		return f.Err.Error()
+	}
+	return f.Message
+}
+
+// Unwrap returns the underlying error.
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Status returns the failure code and phase, which unlike
+// the error message are safe to export to telemetry.
+func (f *Failure) Status() (code, phase string) {
+	return string(f.Code), string(f.Phase)
+}
+
+// Fail returns a Failure with the code, phase and user
+// message that wraps the error. If the error already wraps
+// a Failure, the error is returned unchanged, so that the
+// most specific failure is reported.
+func Fail(code Code, phase Phase, message string, err error) error {
+	if errors.As(err, new(*Failure)) {
+		return err
+	}
+	return &Failure{
+		Code:    code,
+		Phase:   phase,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// AsFailure returns the Failure for the error. Timeout,
+// cancellation and decode errors are converted to the
+// equivalent Failure, and any other error is reported as
+// an unknown failure. It returns nil if the error is nil.
+func AsFailure(err error) *Failure {
+	if err == nil {
+		return nil
+	}
+	if failure := new(Failure); errors.As(err, &failure) {
+		return failure
+	}
+	if timeout := new(TimeoutError); errors.As(err, &timeout) {
+		return &Failure{
+			Code:    CodeTimeout,
+			Phase:   timeout.Phase,
+			Message: timeout.Error(),
+			Err:     err,
+		}
+	}
+	if errors.Is(err, ErrCanceled) || errors.Is(err, context.Canceled) {
+		return &Failure{
+			Code:    CodeCanceled,
+			Message: "task canceled",
+			Err:     err,
+		}
+	}
+	if decodeErr := new(DecodeError); errors.As(err, &decodeErr) {
+		return &Failure{
+			Code:    CodeInvalid,
+			Phase:   PhaseExec,
+			Message: decodeErr.Error(),
+			Err:     err,
+		}
+	}
+	return &Failure{
+		Code:    CodeUnknown,
+		Message: "task failed",
+		Err:     err,
+	}
+}
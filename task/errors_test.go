@@ -0,0 +1,93 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFail(t *testing.T) {
+	inner := Fail(CodeDownload, PhaseDownload, "failed to download", errors.New("404 not found"))
+	outer := Fail(CodeExec, PhaseExec, "failed to execute", inner)
+	if outer != inner {
+		t.Errorf("Expect the most specific failure is kept")
+	}
+	if got, want := outer.Error(), "404 not found"; got != want {
+		t.Errorf("Want error %s, got %s", want, got)
+	}
+
+	b, _ := json.Marshal(AsFailure(outer))
+	if got, want := string(b), `{"code":"download_failed","phase":"download","retryable":false,"message":"failed to download"}`; got != want {
+		t.Errorf("Want json %s, got %s", want, got)
+	}
+}
+
+func TestAsFailure(t *testing.T) {
+	tests := []struct {
+		err   error
+		code  Code
+		phase Phase
+	}{
+		{&TimeoutError{Phase: PhaseBuild}, CodeTimeout, PhaseBuild},
+		{ErrCanceled, CodeCanceled, ""},
+		{context.Canceled, CodeCanceled, ""},
+		{&DecodeError{Field: "name", Err: errors.New("required")}, CodeInvalid, PhaseExec},
+		{errors.New("boom"), CodeUnknown, ""},
+	}
+	for _, test := range tests {
+		got := AsFailure(test.err)
+		if got.Code != test.code || got.Phase != test.phase {
+			t.Errorf("Want failure %s/%s for %v, got %s/%s", test.code, test.phase, test.err, got.Code, got.Phase)
+		}
+	}
+	if AsFailure(nil) != nil {
+		t.Errorf("Want nil failure for nil error")
+	}
+}
+
+func TestRouter_Failure(t *testing.T) {
+	router := NewRouter()
+	router.RegisterDriver("cgi", &testDriver{func(_ context.Context, req *Request) Response {
+		return Respond(&CGITaskResponse{
+			StatusCode: 503,
+			Body:       base64.StdEncoding.EncodeToString([]byte("unavailable")),
+		})
+	}})
+
+	tests := []struct {
+		req   *Request
+		code  Code
+		phase Phase
+	}{
+		{
+			req:   &Request{Task: &Task{Type: "ping"}},
+			code:  CodeNotFound,
+			phase: PhaseRoute,
+		},
+		{
+			req: &Request{
+				Task:  &Task{Type: "ping"},
+				Tasks: []*Task{{ID: "secret", Driver: "cgi"}},
+			},
+			code:  CodeTaskFailed,
+			phase: PhaseExec,
+		},
+	}
+	for _, test := range tests {
+		res := router.Handle(noContext, test.req)
+		failure := new(Failure)
+		if !errors.As(res.Error(), &failure) {
+			t.Errorf("Want failure, got %v", res.Error())
+			continue
+		}
+		if failure.Code != test.code || failure.Phase != test.phase {
+			t.Errorf("Want failure %s/%s, got %s/%s", test.code, test.phase, failure.Code, failure.Phase)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package executor provides asynchronous task execution
+// with status tracking.
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+)
+
+var (
+	// ErrNotFound is returned when no task execution
+	// exists for the request identifier.
+	ErrNotFound = errors.New("task execution not found")
+
+	// ErrDuplicate is returned when a task execution
+	// already exists for the request identifier.
+	ErrDuplicate = errors.New("task execution already exists")
+
+	// ErrRunning is returned when the result is requested
+	// for a task execution that is not complete.
+	ErrRunning = errors.New("task execution not complete")
+
+	// ErrNoID is returned when the request does not
+	// provide a request identifier.
+	ErrNoID = errors.New("task request id not provided")
+)
+
+// now returns the current time, as a function for mocking.
+var now = time.Now
+
+// Executor executes task requests asynchronously and
+// tracks the execution state by request identifier.
+type Executor struct {
+	handler task.Handler
+
+	mu    sync.Mutex
+	execs map[string]*execution
+}
+
+// execution tracks a single task execution.
+type execution struct {
+	state task.State
+	res   task.Response
+	done  chan struct{}
+}
+
+// New returns an executor that executes task requests
+// using the provided handler.
+func New(handler task.Handler) *Executor {
+	return &Executor{
+		handler: handler,
+		execs:   map[string]*execution{},
+	}
+}
+
+// Submit accepts the task request for execution and returns
+// immediately with the pending task state. The request is
+// keyed by the request identifier.
+//
+// The task execution is detached from the cancellation of
+// the provided context, but retains the context values.
+func (e *Executor) Submit(ctx context.Context, req *task.Request) (*task.State, error) {
+	if req.ID == "" {
+		return nil, ErrNoID
+	}
+
+	e.mu.Lock()
+	if _, ok := e.execs[req.ID]; ok {
+		e.mu.Unlock()
+		return nil, ErrDuplicate
+	}
+	exec := &execution{
+		state: task.State{
+			ID:     req.ID,
+			Status: task.StatusPending,
+		},
+		done: make(chan struct{}),
+	}
+	e.execs[req.ID] = exec
+	state := exec.state
+	e.mu.Unlock()
+
+	go e.run(context.WithoutCancel(ctx), req, exec)
+	return &state, nil
+}
+
+// run executes the task request and records the result.
+func (e *Executor) run(ctx context.Context, req *task.Request, exec *execution) {
+	defer close(exec.done)
+
+	e.mu.Lock()
+	exec.state.Status = task.StatusRunning
+	exec.state.Started = now().Unix()
+	e.mu.Unlock()
+
+	res := e.handler.Handle(ctx, req)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exec.res = res
+	exec.state.Finished = now().Unix()
+	if res != nil && res.Error() != nil {
+		exec.state.Status = task.StatusFailure
+		logger.FromContext(ctx).
+			WithError(res.Error()).
+			WithField("request.id", req.ID).
+			Debug("task execution failed")
+	} else {
+		exec.state.Status = task.StatusSuccess
+	}
+}
+
+// Status returns the task execution state.
+func (e *Executor) Status(id string) (*task.State, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exec, ok := e.execs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	state := exec.state
+	return &state, nil
+}
+
+// Result returns the task execution result. An error is
+// returned if the task execution is not complete.
+func (e *Executor) Result(id string) (task.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exec, ok := e.execs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !exec.state.Status.Done() {
+		return nil, ErrRunning
+	}
+	return exec.res, nil
+}
+
+// Wait blocks until the task execution is complete or
+// the context is cancelled, and returns the result.
+func (e *Executor) Wait(ctx context.Context, id string) (task.Response, error) {
+	e.mu.Lock()
+	exec, ok := e.execs[id]
+	e.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	select {
+	case <-exec.done:
+		return e.Result(id)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Remove removes a completed task execution. An error is
+// returned if the task execution is not complete.
+func (e *Executor) Remove(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exec, ok := e.execs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !exec.state.Status.Done() {
+		return ErrRunning
+	}
+	delete(e.execs, id)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+)
+
+func TestExecutor(t *testing.T) {
+	release := make(chan struct{})
+	router := task.NewRouter()
+	router.RegisterFunc("ping", func(_ context.Context, req *task.Request) task.Response {
+		<-release
+		return task.Respond("pong")
+	})
+	router.RegisterFunc("fail", func(_ context.Context, req *task.Request) task.Response {
+		return task.Errorf("ping error")
+	})
+
+	e := New(router)
+	state, err := e.Submit(context.Background(), &task.Request{ID: "1", Task: &task.Task{Type: "ping"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := state.Status, task.StatusPending; got != want {
+		t.Errorf("Want status %s, got %s", want, got)
+	}
+	if _, err := e.Submit(context.Background(), &task.Request{ID: "1", Task: &task.Task{Type: "ping"}}); err != ErrDuplicate {
+		t.Errorf("Want duplicate error, got %v", err)
+	}
+	if _, err := e.Result("1"); err != ErrRunning {
+		t.Errorf("Want running error, got %v", err)
+	}
+	if err := e.Remove("1"); err != ErrRunning {
+		t.Errorf("Want running error, got %v", err)
+	}
+
+	close(release)
+
+	res, err := e.Wait(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(res.Body()), "pong"; got != want {
+		t.Errorf("Want response body %s, got %s", want, got)
+	}
+	state, _ = e.Status("1")
+	if got, want := state.Status, task.StatusSuccess; got != want {
+		t.Errorf("Want status %s, got %s", want, got)
+	}
+	if state.Started == 0 || state.Finished == 0 {
+		t.Errorf("Want start and finish timestamps")
+	}
+
+	e.Submit(context.Background(), &task.Request{ID: "2", Task: &task.Task{Type: "fail"}})
+	e.Wait(context.Background(), "2")
+	state, _ = e.Status("2")
+	if got, want := state.Status, task.StatusFailure; got != want {
+		t.Errorf("Want status %s, got %s", want, got)
+	}
+
+	if err := e.Remove("1"); err != nil {
+		t.Error(err)
+	}
+	if _, err := e.Status("1"); err != ErrNotFound {
+		t.Errorf("Want not found error, got %v", err)
+	}
+}
+
+func TestExecutor_WaitCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	e := New(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+		<-release
+		return nil
+	}))
+	e.Submit(context.Background(), &task.Request{ID: "1", Task: &task.Task{}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := e.Wait(ctx, "1"); err != context.DeadlineExceeded {
+		t.Errorf("Want deadline exceeded, got %v", err)
+	}
+}
+
+func TestExecutor_NoID(t *testing.T) {
+	e := New(task.NewRouter())
+	if _, err := e.Submit(context.Background(), &task.Request{Task: &task.Task{}}); err != ErrNoID {
+		t.Errorf("Want no id error, got %v", err)
+	}
+}
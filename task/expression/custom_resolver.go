@@ -19,10 +19,11 @@ import (
  */
 type CustomResolver struct {
 	secrets []*common.Secret
+	outputs map[string]string
 }
 
-func newCustomResolver(secrets []*common.Secret) *CustomResolver {
-	return &CustomResolver{secrets: secrets}
+func newCustomResolver(secrets []*common.Secret, outputs map[string]string) *CustomResolver {
+	return &CustomResolver{secrets: secrets, outputs: outputs}
 }
 
 func (r *CustomResolver) Resolve(data []byte) ([]byte, error) {
@@ -36,6 +37,7 @@ func (r *CustomResolver) Resolve(data []byte) ([]byte, error) {
 
 	// evaluate the expressions
 	evaler.Eval(v, r.secrets)
+	evaler.EvalOutputs(v, r.outputs)
 
 	// encode the map back to []byte using a custom encoder that doesn't escape HTML
 	buf := &bytes.Buffer{}
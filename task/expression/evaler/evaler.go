@@ -15,18 +15,34 @@ import (
 
 // Eval evaluates expressions in the map structure.
 func Eval(data map[string]any, secrets []*common.Secret) {
+	eval(data, func(s string) string {
+		return resolveSecrets(s, secrets)
+	})
+}
+
+// EvalOutputs evaluates task output expressions in the
+// map structure, for example ${{outputs.build.version}}.
+func EvalOutputs(data map[string]any, outputs map[string]string) {
+	eval(data, func(s string) string {
+		return resolveOutputs(s, outputs)
+	})
+}
+
+// eval walks the map structure and replaces expressions
+// in string values using the resolve function.
+func eval(data map[string]any, resolve func(string) string) {
 	var walk func(any) (bool, string)
 
 	// helper function to walk the map and inject
-	// secret variables in child keys where the
-	// value is a reference to a $secret.
+	// variables in child keys where the value is
+	// an expression.
 	walk = func(i any) (_ bool, _ string) {
 		switch v := i.(type) {
 		case string:
 			if !strings.Contains(v, "${{") {
 				return
 			}
-			v = resolveSecrets(v, secrets)
+			v = resolve(v)
 			return true, v
 		case []any:
 			for i := 0; i < len(v); i++ {
@@ -60,3 +76,10 @@ func resolveSecrets(s string, secrets []*common.Secret) string {
 	}
 	return s
 }
+
+func resolveOutputs(s string, outputs map[string]string) string {
+	for key, value := range outputs {
+		s = strings.ReplaceAll(s, "${{outputs."+key+"}}", value)
+	}
+	return s
+}
@@ -45,3 +45,21 @@ func TestEval(t *testing.T) {
 		t.Log(diff)
 	}
 }
+
+func TestEvalOutputs(t *testing.T) {
+	input := map[string]any{
+		"version": "v${{outputs.build.version}}",
+		"missing": "${{outputs.build.missing}}",
+	}
+
+	EvalOutputs(input, map[string]string{"build.version": "1.2.3"})
+
+	got, want := input, map[string]any{
+		"version": "v1.2.3",
+		"missing": "${{outputs.build.missing}}",
+	}
+	if diff := cmp.Diff(got, want); len(diff) != 0 {
+		t.Error("Unexpected output expansion")
+		t.Log(diff)
+	}
+}
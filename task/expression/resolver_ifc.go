@@ -12,19 +12,27 @@ type ResolverIfc interface {
 
 type Resolver struct {
 	secrets []*common.Secret
+	outputs map[string]string
 }
 
 func New(secrets []*common.Secret) *Resolver {
 	return &Resolver{secrets: secrets}
 }
 
+// WithOutputs sets the task outputs that are referenced
+// using the ${{outputs.<task>.<name>}} syntax.
+func (r *Resolver) WithOutputs(outputs map[string]string) *Resolver {
+	r.outputs = outputs
+	return r
+}
+
 func (r *Resolver) Resolve(taskData []byte) ([]byte, []string, error) {
 	// Start with the original task data
 	currentData := taskData
 
-	// First pass: Handle custom secrets syntax (${{secrets...}})
-	if bytes.Contains(currentData, []byte("${{secrets")) {
-		customResolver := newCustomResolver(r.secrets)
+	// First pass: Handle custom secrets and outputs syntax (${{secrets...}}, ${{outputs...}})
+	if bytes.Contains(currentData, []byte("${{secrets")) || bytes.Contains(currentData, []byte("${{outputs")) {
+		customResolver := newCustomResolver(r.secrets, r.outputs)
 		resolvedData, err := customResolver.Resolve(currentData)
 		if err != nil {
 			return nil, nil, err
@@ -0,0 +1,184 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package forward provides support for forwarding tasks
+// to another runner node in the network.
+package forward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+	"github.com/drone/go-task/task/logger"
+)
+
+type (
+	// request is the wire format of a forwarded task request.
+	request struct {
+		*task.Request
+
+		// Secrets provides the resolved secrets, which are
+		// excluded from the default request encoding.
+		Secrets []*common.Secret `json:"resolved_secrets,omitempty"`
+	}
+
+	// response is the wire format of a forwarded task response.
+	response struct {
+		Data    []byte            `json:"data,omitempty"`
+		Error   string            `json:"error,omitempty"`
+		Outputs map[string]string `json:"outputs,omitempty"`
+		Secrets map[string]string `json:"secrets,omitempty"`
+	}
+)
+
+// RemoteError is returned when the remote runner node
+// fails to execute the task.
+type RemoteError struct {
+	Address string
+	Cause   string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("forward %s: %s", e.Address, e.Cause)
+}
+
+// Handler returns a middleware that forwards the task
+// request to a remote runner node when the task includes
+// forwarding instructions. Otherwise the request is handled
+// by the next handler.
+//
+// The middleware is typically wrapped around the router,
+// so that secret sub-tasks are resolved by the remote
+// runner node and not by the forwarding node.
+func Handler(next task.Handler) task.Handler {
+	return task.HandlerFunc(func(ctx context.Context, req *task.Request) task.Response {
+		if req.Task == nil || req.Task.Forward == nil {
+			return next.Handle(ctx, req)
+		}
+		return forward(ctx, req)
+	})
+}
+
+// forward sends the task request to the remote runner
+// node and returns the remote response.
+func forward(ctx context.Context, req *task.Request) task.Response {
+	conf := req.Task.Forward
+	if conf.Address == "" {
+		return task.Error(task.Fail(task.CodeInvalid, task.PhaseRoute, "forward address is required", nil))
+	}
+	log := logger.FromContext(ctx).
+		WithField("forward.address", conf.Address)
+
+	client, err := newClient(conf)
+	if err != nil {
+		return task.Error(fmt.Errorf("forward %s: %w", conf.Address, err))
+	}
+
+	// copy the task and remove the forwarding instructions
+	// to prevent the remote node forwarding the task again.
+	t := *req.Task
+	t.Forward = nil
+
+	in := *req
+	in.Task = &t
+
+	body, err := json.Marshal(&request{Request: &in, Secrets: req.Secrets})
+	if err != nil {
+		return task.Error(err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(conf.Address), bytes.NewReader(body))
+	if err != nil {
+		return task.Error(fmt.Errorf("forward %s: %w", conf.Address, err))
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	log.Debug("forward task")
+
+	res, err := client.Do(r)
+	if err != nil {
+		log.WithError(err).Error("could not forward task")
+		return task.Error(fmt.Errorf("forward %s: %w", conf.Address, err))
+	}
+	defer res.Body.Close()
+
+	out := new(response)
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		if res.StatusCode > 299 {
+			return task.Error(&RemoteError{Address: conf.Address, Cause: res.Status})
+		}
+		return task.Error(fmt.Errorf("forward %s: cannot decode response: %w", conf.Address, err))
+	}
+	if out.Error != "" {
+		return task.Error(&RemoteError{Address: conf.Address, Cause: out.Error})
+	}
+	return &task.Result{
+		Data:    out.Data,
+		Outputs: out.Outputs,
+		Secrets: out.Secrets,
+	}
+}
+
+// Server returns an http.Handler that executes forwarded
+// task requests using the provided handler. The server
+// should be started with the tls configuration returned
+// by ServerTLSConfig.
+func Server(h task.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeResponse(w, http.StatusMethodNotAllowed, &response{Error: "method not allowed"})
+			return
+		}
+
+		in := &request{Request: new(task.Request)}
+		if err := json.NewDecoder(r.Body).Decode(in); err != nil {
+			writeResponse(w, http.StatusBadRequest, &response{Error: err.Error()})
+			return
+		}
+		if in.Request.Task == nil {
+			writeResponse(w, http.StatusBadRequest, &response{Error: "no task provided"})
+			return
+		}
+		req := in.Request
+		req.Secrets = in.Secrets
+		req.Logger = io.Discard
+
+		res := h.Handle(r.Context(), req)
+
+		out := new(response)
+		if res != nil {
+			out.Data = res.Body()
+			if err := res.Error(); err != nil {
+				out.Error = err.Error()
+			}
+			if result, ok := res.(*task.Result); ok {
+				out.Outputs = result.Outputs
+				out.Secrets = result.Secrets
+			}
+		}
+		writeResponse(w, http.StatusOK, out)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, code int, res *response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
+
+// endpoint returns the remote endpoint url. The address
+// defaults to https if no scheme is provided.
+func endpoint(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	return address
+}
@@ -0,0 +1,213 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+)
+
+func TestForward(t *testing.T) {
+	server, client := testCerts(t)
+
+	remote := task.NewRouter()
+	remote.RegisterFunc("ping", func(_ context.Context, req *task.Request) task.Response {
+		if req.Task.Forward != nil {
+			t.Errorf("Expect forwarding instructions removed")
+		}
+		if len(req.Secrets) != 1 || req.Secrets[0].Value != "mySecret" {
+			t.Errorf("Expect resolved secrets forwarded")
+		}
+		return task.Respond("pong")
+	})
+	remote.RegisterFunc("fail", func(_ context.Context, req *task.Request) task.Response {
+		return task.Errorf("remote failure")
+	})
+
+	ts := httptest.NewUnstartedServer(Server(remote))
+	config, err := ServerTLSConfig(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	local := Handler(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+		t.Errorf("Expect task forwarded")
+		return nil
+	}))
+
+	res := local.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:    "ping",
+			Forward: &task.Forward{Address: ts.Listener.Addr().String(), Certs: client},
+		},
+		Secrets: []*common.Secret{{ID: "token", Value: "mySecret"}},
+	})
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(res.Body()), "pong"; got != want {
+		t.Errorf("Want response body %s, got %s", want, got)
+	}
+
+	res = local.Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:    "fail",
+			Forward: &task.Forward{Address: ts.Listener.Addr().String(), Certs: client},
+		},
+	})
+	remoteErr := new(RemoteError)
+	if !errors.As(res.Error(), &remoteErr) {
+		t.Fatalf("Want remote error, got %v", res.Error())
+	}
+	if got, want := remoteErr.Cause, "remote failure"; got != want {
+		t.Errorf("Want remote cause %s, got %s", want, got)
+	}
+}
+
+func TestForward_Decode(t *testing.T) {
+	server, client := testCerts(t)
+
+	remote := task.NewRouter()
+	remote.RegisterFunc("ping", func(context.Context, *task.Request) task.Response {
+		return task.Respond("pong")
+	})
+	ts := httptest.NewUnstartedServer(Server(remote))
+	config, err := ServerTLSConfig(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	certs, _ := json.Marshal(client)
+	data := fmt.Sprintf(`{"task": {"type": "ping", "forward": {"address": %q, "certs": %s}}}`,
+		ts.Listener.Addr().String(), certs)
+
+	req := new(task.Request)
+	if err := json.Unmarshal([]byte(data), req); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := req.Task.Forward.Address, ts.Listener.Addr().String(); got != want {
+		t.Fatalf("Want forward address %s, got %q", want, got)
+	}
+	res := Handler(nil).Handle(context.Background(), req)
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(res.Body()), "pong"; got != want {
+		t.Errorf("Want response body %s, got %s", want, got)
+	}
+}
+
+func TestForward_NoAddress(t *testing.T) {
+	res := Handler(nil).Handle(context.Background(), &task.Request{
+		Task: &task.Task{Type: "ping", Forward: &task.Forward{}},
+	})
+	if got, want := task.AsFailure(res.Error()).Code, task.CodeInvalid; got != want {
+		t.Errorf("Want failure code %s, got %s", want, got)
+	}
+}
+
+func TestForward_NoClientCert(t *testing.T) {
+	server, client := testCerts(t)
+
+	ts := httptest.NewUnstartedServer(Server(task.NewRouter()))
+	config, err := ServerTLSConfig(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	res := Handler(nil).Handle(context.Background(), &task.Request{
+		Task: &task.Task{
+			Type:    "ping",
+			Forward: &task.Forward{Address: ts.Listener.Addr().String(), Certs: task.Certs{CA: client.CA}},
+		},
+	})
+	if res.Error() == nil {
+		t.Errorf("Expect error when client certificate is missing")
+	}
+}
+
+func TestForward_Skip(t *testing.T) {
+	var visited bool
+	h := Handler(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+		visited = true
+		return task.Respond("pong")
+	}))
+	h.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "ping"}})
+	if !visited {
+		t.Errorf("Expect next handler invoked")
+	}
+}
+
+// testCerts returns server and client certificates signed
+// by the same certificate authority.
+func testCerts(t *testing.T) (server, client task.Certs) {
+	t.Helper()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) task.Certs {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		return task.Certs{
+			Public:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			Private: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+			CA:      caPEM,
+		}
+	}
+	return issue(2, x509.ExtKeyUsageServerAuth), issue(3, x509.ExtKeyUsageClientAuth)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package forward
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	"github.com/drone/go-task/task"
+)
+
+// newClient returns an http client configured for mutual
+// tls using the forwarding certificates.
+func newClient(conf *task.Forward) (*http.Client, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: conf.Insecure,
+	}
+	if len(conf.Certs.Public) != 0 || len(conf.Certs.Private) != 0 {
+		cert, err := tls.X509KeyPair(conf.Certs.Public, conf.Certs.Private)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(conf.Certs.CA) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(conf.Certs.CA) {
+			return nil, errors.New("cannot parse ca certificate")
+		}
+		config.RootCAs = pool
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: config,
+		},
+	}, nil
+}
+
+// ServerTLSConfig returns the tls configuration for a
+// runner node that accepts forwarded tasks. Client
+// certificates are required and verified using the
+// certificate authority.
+func ServerTLSConfig(certs task.Certs) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(certs.Public, certs.Private)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certs.CA) {
+		return nil, errors.New("cannot parse ca certificate")
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
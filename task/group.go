@@ -0,0 +1,159 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"sort"
+	"strings"
+)
+
+// route is a handler registered to the router.
+type route struct {
+	pattern string
+	handler Handler
+	group   *Group
+}
+
+// endpoint returns the route handler wrapped in the
+// middleware stack of the route group.
+func (r *route) endpoint() Handler {
+	if r.group == nil {
+		return r.handler
+	}
+	return chain(r.group.stack(), r.handler)
+}
+
+// wildcard returns true if the route pattern matches
+// task types by prefix.
+func (r *route) wildcard() bool {
+	return strings.HasSuffix(r.pattern, "*")
+}
+
+// prefix returns the prefix matched by a wildcard route.
+func (r *route) prefix() string {
+	return strings.TrimSuffix(r.pattern, "*")
+}
+
+// routes is a collection of exact and wildcard routes.
+type routes struct {
+	exact     map[string]*route
+	wildcards []*route // sorted by prefix length, longest first
+}
+
+// add adds the route to the collection, replacing any
+// route registered with the same pattern.
+func (r *routes) add(rt *route) {
+	if !rt.wildcard() {
+		if r.exact == nil {
+			r.exact = map[string]*route{}
+		}
+		r.exact[rt.pattern] = rt
+		return
+	}
+	for i, existing := range r.wildcards {
+		if existing.pattern == rt.pattern {
+			r.wildcards[i] = rt
+			return
+		}
+	}
+	r.wildcards = append(r.wildcards, rt)
+	sort.SliceStable(r.wildcards, func(i, j int) bool {
+		return len(r.wildcards[i].pattern) > len(r.wildcards[j].pattern)
+	})
+}
+
+// all returns all routes sorted by pattern.
+func (r *routes) all() []*route {
+	var out []*route
+	for _, rt := range r.exact {
+		out = append(out, rt)
+	}
+	out = append(out, r.wildcards...)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].pattern < out[j].pattern
+	})
+	return out
+}
+
+// match returns the route for the task type. An exact
+// match takes precedence, followed by the wildcard route
+// with the longest matching prefix.
+func (r *routes) match(name string) (*route, bool) {
+	if rt, ok := r.exact[name]; ok {
+		return rt, true
+	}
+	for _, rt := range r.wildcards {
+		if strings.HasPrefix(name, rt.prefix()) {
+			return rt, true
+		}
+	}
+	return nil, false
+}
+
+// Group is a group of routes that share a task type
+// prefix and a middleware stack. The group middleware
+// is executed after the router middleware.
+type Group struct {
+	router     *Router
+	parent     *Group
+	prefix     string
+	middleware []func(Handler) Handler
+}
+
+// Use adds the middleware onto the group stack.
+func (g *Group) Use(fn func(Handler) Handler) {
+	g.middleware = append(g.middleware, fn)
+}
+
+// Register registers the Handler to the router, using
+// the group prefix and middleware.
+func (g *Group) Register(name string, handler Handler) {
+	g.router.routes.add(&route{
+		pattern: joinPattern(g.prefix, name),
+		handler: handler,
+		group:   g,
+	})
+}
+
+// RegisterFunc registers the HandlerFunc to the router,
+// using the group prefix and middleware.
+func (g *Group) RegisterFunc(name string, handler HandlerFunc) {
+	g.Register(name, HandlerFunc(handler))
+}
+
+// Route creates a nested group with the prefix, and
+// invokes fn to register the group routes.
+func (g *Group) Route(prefix string, fn func(*Group)) *Group {
+	sub := &Group{
+		router: g.router,
+		parent: g,
+		prefix: joinPattern(g.prefix, prefix),
+	}
+	if fn != nil {
+		fn(sub)
+	}
+	return sub
+}
+
+// stack returns the middleware stack of the group,
+// including the middleware of the parent groups.
+func (g *Group) stack() []func(Handler) Handler {
+	if g.parent == nil {
+		return g.middleware
+	}
+	return append(append([]func(Handler) Handler{}, g.parent.stack()...), g.middleware...)
+}
+
+// joinPattern joins the group prefix and the name.
+func joinPattern(prefix, name string) string {
+	switch {
+	case prefix == "":
+		return name
+	case name == "":
+		return prefix
+	default:
+		return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(name, "/")
+	}
+}
@@ -19,3 +19,12 @@ type HandlerFunc func(context.Context, *Request) Response
 func (f HandlerFunc) Handle(ctx context.Context, req *Request) Response {
 	return f(ctx, req)
 }
+
+// A Decoder decodes the task output from a driver-specific
+// response envelope. Drivers that wrap the task output,
+// for example in a CGITaskResponse, implement Decoder so
+// that callers can extract the output without knowing
+// which driver handled the task.
+type Decoder interface {
+	Decode(Response) ([]byte, error)
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package idempotent provides idempotent execution of task
+// requests, keyed by the request identifier and task.
+package idempotent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/middleware"
+)
+
+// DefaultTTL provides the default duration for which the
+// results of completed tasks are stored.
+const DefaultTTL = 10 * time.Minute
+
+// Config configures the idempotent handler.
+type Config struct {
+	// Store provides the store for the results of completed
+	// tasks. Defaults to an in-memory store.
+	Store Store
+
+	// TTL provides the duration for which the results of
+	// completed tasks are stored. Defaults to DefaultTTL.
+	TTL time.Duration
+}
+
+// Handler executes each task request at most once per
+// request identifier and task. Concurrent duplicates of a
+// request share one execution, and duplicates received after
+// the execution completes are replayed the stored result. A
+// request that reuses an identifier for a different task is
+// executed. Requests without an identifier are always
+// executed.
+type Handler struct {
+	handler task.Handler
+	store   Store
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]*call
+}
+
+// call is an in-flight execution.
+type call struct {
+	done chan struct{}
+	res  task.Response
+}
+
+// New returns an idempotent handler that executes tasks
+// using the provided handler. Panics in the handler are
+// recovered, so that the duplicates waiting on the execution
+// are released.
+func New(handler task.Handler, config Config) *Handler {
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+	return &Handler{
+		handler:  middleware.Recover(handler),
+		store:    config.Store,
+		ttl:      config.TTL,
+		inflight: map[string]*call{},
+	}
+}
+
+// Handle executes the task request, or returns the result
+// of the execution of a duplicate request.
+func (h *Handler) Handle(ctx context.Context, req *task.Request) task.Response {
+	if req.ID == "" {
+		return h.handler.Handle(ctx, req)
+	}
+	key := requestKey(req)
+
+	log := logger.FromContext(ctx).WithField("request.id", req.ID)
+
+	h.mu.Lock()
+	c, ok := h.inflight[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		h.inflight[key] = c
+	}
+	h.mu.Unlock()
+
+	if ok {
+		log.Debug("waiting for duplicate task execution")
+	} else {
+		// the execution does not inherit the cancellation of
+		// the request, so that cancelling one request does not
+		// fail the duplicates waiting on the execution.
+		go h.execute(context.WithoutCancel(ctx), key, req, c)
+	}
+
+	select {
+	case <-c.done:
+		return c.res
+	case <-ctx.Done():
+		return task.Error(ctx.Err())
+	}
+}
+
+// execute replays the stored result of the task request,
+// or executes the task request and stores the result, and
+// releases the duplicates waiting on the call.
+func (h *Handler) execute(ctx context.Context, key string, req *task.Request, c *call) {
+	log := logger.FromContext(ctx).WithField("request.id", req.ID)
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.inflight, key)
+		h.mu.Unlock()
+		close(c.done)
+	}()
+
+	// the store is checked after the call is registered, so
+	// that a result stored by a completed execution is not
+	// missed.
+	stored, ok, err := h.store.Get(ctx, key)
+	if err != nil {
+		log.WithError(err).Warn("cannot get stored task result")
+	}
+	if ok {
+		log.Debug("replaying stored task result")
+		c.res = stored.response()
+		return
+	}
+
+	c.res = h.handler.Handle(ctx, req)
+
+	// cancelled, timed out and transient failures are not
+	// stored, so that a retried delivery executes the task.
+	if storable(c.res) {
+		if err := h.store.Put(ctx, key, newResult(c.res), h.ttl); err != nil {
+			log.WithError(err).Warn("cannot store task result")
+		}
+	}
+}
+
+// storable reports whether the result can be replayed.
+func storable(res task.Response) bool {
+	if res == nil {
+		return false
+	}
+	failure := task.AsFailure(res.Error())
+	if failure == nil {
+		return true
+	}
+	switch failure.Code {
+	case task.CodeCanceled, task.CodeTimeout:
+		return false
+	}
+	return !failure.Retryable
+}
+
+// requestKey returns the key of the request, which includes
+// a hash of the task, so that a different task that reuses
+// the request identifier is not replayed the stored result
+// of another task.
+func requestKey(req *task.Request) string {
+	hash := sha256.New()
+	for _, b := range [][]byte{
+		[]byte(req.Task.Type),
+		[]byte(req.Task.Driver),
+		req.Task.Data,
+		req.Task.Config,
+	} {
+		hash.Write(b)
+		hash.Write([]byte{0})
+	}
+	return req.ID + ":" + hex.EncodeToString(hash.Sum(nil))
+}
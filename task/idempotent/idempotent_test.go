@@ -0,0 +1,211 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package idempotent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+)
+
+// counter is a handler that counts executions and blocks
+// until released.
+type counter struct {
+	count   atomic.Int32
+	release chan struct{}
+	res     task.Response
+}
+
+func (c *counter) Handle(context.Context, *task.Request) task.Response {
+	c.count.Add(1)
+	<-c.release
+	return c.res
+}
+
+func TestHandler_Concurrent(t *testing.T) {
+	c := &counter{release: make(chan struct{}), res: task.Respond("pong")}
+	h := New(c, Config{})
+
+	var wg sync.WaitGroup
+	results := make([]task.Response, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.Handle(context.Background(), &task.Request{ID: "1", Task: &task.Task{}})
+		}()
+	}
+
+	// wait for the duplicates to join the execution.
+	for c.count.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(c.release)
+	wg.Wait()
+
+	if got := c.count.Load(); got != 1 {
+		t.Errorf("Want 1 execution, got %d", got)
+	}
+	for _, res := range results {
+		if string(res.Body()) != "pong" {
+			t.Errorf("Want shared result, got %s", res.Body())
+		}
+	}
+}
+
+func TestHandler_Replay(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+	current := time.Now()
+	now = func() time.Time { return current }
+
+	c := &counter{release: make(chan struct{}), res: task.Respond("pong")}
+	close(c.release)
+	h := New(c, Config{TTL: time.Minute})
+
+	req := &task.Request{ID: "1", Task: &task.Task{}}
+	h.Handle(context.Background(), req)
+	h.Handle(context.Background(), req)
+	if got := c.count.Load(); got != 1 {
+		t.Errorf("Want result replayed within the ttl, got %d executions", got)
+	}
+
+	current = current.Add(time.Minute)
+	h.Handle(context.Background(), req)
+	if got := c.count.Load(); got != 2 {
+		t.Errorf("Want task executed after the ttl, got %d executions", got)
+	}
+
+	// requests without an identifier are always executed.
+	h.Handle(context.Background(), &task.Request{Task: &task.Task{}})
+	if got := c.count.Load(); got != 3 {
+		t.Errorf("Want task executed without request id, got %d executions", got)
+	}
+}
+
+func TestHandler_DifferentTask(t *testing.T) {
+	c := &counter{release: make(chan struct{}), res: task.Respond("pong")}
+	close(c.release)
+	h := New(c, Config{})
+
+	h.Handle(context.Background(), &task.Request{ID: "1", Task: &task.Task{Type: "a", Data: []byte(`{"n":1}`)}})
+	h.Handle(context.Background(), &task.Request{ID: "1", Task: &task.Task{Type: "a", Data: []byte(`{"n":2}`)}})
+	h.Handle(context.Background(), &task.Request{ID: "1", Task: &task.Task{Type: "b", Data: []byte(`{"n":2}`)}})
+	if got := c.count.Load(); got != 3 {
+		t.Errorf("Want different tasks with the same request id executed, got %d executions", got)
+	}
+}
+
+func TestHandler_Canceled(t *testing.T) {
+	c := &counter{release: make(chan struct{}), res: task.Error(task.ErrCanceled)}
+	close(c.release)
+	h := New(c, Config{})
+
+	req := &task.Request{ID: "1", Task: &task.Task{}}
+	h.Handle(context.Background(), req)
+	h.Handle(context.Background(), req)
+	if got := c.count.Load(); got != 2 {
+		t.Errorf("Expect cancelled result not replayed, got %d executions", got)
+	}
+}
+
+func TestHandler_Panic(t *testing.T) {
+	release := make(chan struct{})
+	h := New(task.HandlerFunc(func(context.Context, *task.Request) task.Response {
+		<-release
+		panic("cannot connect using password hunter2")
+	}), Config{})
+
+	req := &task.Request{
+		ID:      "1",
+		Task:    &task.Task{},
+		Secrets: []*common.Secret{{ID: "password", Value: "hunter2"}},
+	}
+	results := make(chan task.Response, 2)
+	for range 2 {
+		go func() {
+			results <- h.Handle(context.Background(), req)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	for range 2 {
+		res := <-results
+		if res == nil || res.Error() == nil {
+			t.Fatalf("Want error response after panic, got %v", res)
+		}
+		if strings.Contains(res.Error().Error(), "hunter2") {
+			t.Errorf("Want panic value masked, got %s", res.Error())
+		}
+	}
+}
+
+func TestHandler_CancelDuplicate(t *testing.T) {
+	c := &counter{release: make(chan struct{}), res: task.Respond("pong")}
+	h := New(c, Config{})
+	req := &task.Request{ID: "1", Task: &task.Task{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan task.Response)
+	go func() {
+		first <- h.Handle(ctx, req)
+	}()
+	for c.count.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan task.Response)
+	go func() {
+		second <- h.Handle(context.Background(), req)
+	}()
+
+	// cancelling the first request does not cancel the
+	// shared execution.
+	cancel()
+	if res := <-first; res.Error() == nil {
+		t.Errorf("Want cancelled request to return an error")
+	}
+	close(c.release)
+	if res := <-second; string(res.Body()) != "pong" {
+		t.Errorf("Want duplicate to receive the result, got %v", res.Error())
+	}
+	if got := c.count.Load(); got != 1 {
+		t.Errorf("Want 1 execution, got %d", got)
+	}
+}
+
+func TestResult_JSON(t *testing.T) {
+	res := &task.Result{
+		Data:    []byte("pong"),
+		Outputs: map[string]string{"version": "1.0.0"},
+		Err:     task.Fail(task.CodeTaskFailed, task.PhaseExec, "task failed", errors.New("exit status 1")),
+	}
+	b, err := json.Marshal(newResult(res))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := new(Result)
+	if err := json.Unmarshal(b, stored); err != nil {
+		t.Fatal(err)
+	}
+	replayed := stored.response()
+	if string(replayed.Body()) != "pong" || task.Outputs(replayed)["version"] != "1.0.0" {
+		t.Errorf("Want body and outputs replayed")
+	}
+	failure := task.AsFailure(replayed.Error())
+	if failure.Code != task.CodeTaskFailed || failure.Message != "task failed" {
+		t.Errorf("Want failure replayed, got %+v", failure)
+	}
+}
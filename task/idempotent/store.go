@@ -0,0 +1,117 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package idempotent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/drone/go-task/task"
+)
+
+// now returns the current time, and can be replaced
+// in unit tests.
+var now = time.Now
+
+// Store stores the results of completed tasks, keyed by
+// the request identifier and a hash of the task.
+type Store interface {
+	// Get returns the stored result for the key. It returns
+	// false if no result is stored or the result expired.
+	Get(ctx context.Context, key string) (*Result, bool, error)
+
+	// Put stores the result for the key until the ttl
+	// expires.
+	Put(ctx context.Context, key string, res *Result, ttl time.Duration) error
+}
+
+// Result is the stored result of a completed task. It can
+// be encoded as json, so that results can be persisted to
+// a remote store. The task secrets are included, and a
+// persistent store should encrypt the result at rest.
+type Result struct {
+	// Body provides the response body.
+	Body []byte `json:"body,omitempty"`
+
+	// Outputs provides the named outputs of the task.
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// Secrets provides the named secrets created by the
+	// task.
+	Secrets map[string]string `json:"secrets,omitempty"`
+
+	// Error provides the task failure. The underlying error
+	// is not stored.
+	Error *task.Failure `json:"error,omitempty"`
+}
+
+// newResult returns the stored result for the response.
+func newResult(res task.Response) *Result {
+	return &Result{
+		Body:    res.Body(),
+		Outputs: task.Outputs(res),
+		Secrets: task.Secrets(res),
+		Error:   task.AsFailure(res.Error()),
+	}
+}
+
+// response returns the task response for the stored
+// result.
+func (r *Result) response() task.Response {
+	res := &task.Result{
+		Data:    r.Body,
+		Outputs: r.Outputs,
+		Secrets: r.Secrets,
+	}
+	if r.Error != nil {
+		res.Err = r.Error
+	}
+	return res
+}
+
+// NewMemoryStore returns an in-memory Store. Expired
+// results are removed when new results are stored.
+func NewMemoryStore() Store {
+	return &memoryStore{entries: map[string]*entry{}}
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is a stored result.
+type entry struct {
+	res     *Result
+	expires time.Time
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (*Result, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !now().Before(e.expires) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return e.res, true, nil
+}
+
+func (s *memoryStore) Put(_ context.Context, key string, res *Result, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := now()
+	for k, e := range s.entries {
+		if !t.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = &entry{res: res, expires: t.Add(ttl)}
+	return nil
+}
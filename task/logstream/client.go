@@ -0,0 +1,146 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package logstream provides a client for streaming and
+// uploading task logs to the log service.
+package logstream
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Line represents a line in the logs.
+type Line struct {
+	Level     string            `json:"level"`
+	Number    int               `json:"pos"`
+	Message   string            `json:"out"`
+	Timestamp time.Time         `json:"time"`
+	Args      map[string]string `json:"args"`
+}
+
+// Link represents a signed link used to upload logs
+// indirectly to the blob store.
+type Link struct {
+	Value   string    `json:"link"`
+	Expires time.Time `json:"expires"`
+}
+
+// Client is a log service client.
+type Client struct {
+	client   *http.Client
+	endpoint string
+	account  string
+	token    string
+}
+
+// New returns a new log service client.
+func New(endpoint, account, token string, insecure bool) *Client {
+	client := http.DefaultClient
+	if insecure {
+		client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true, // user-configured
+				},
+			},
+		}
+	}
+	return &Client{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		account:  account,
+		token:    token,
+	}
+}
+
+// Open opens the log stream.
+func (c *Client) Open(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodPost, c.url("/stream", key), nil, nil)
+}
+
+// Close closes the log stream.
+func (c *Client) Close(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodDelete, c.url("/stream", key)+"&snapshot=true", nil, nil)
+}
+
+// Write writes the lines to the log stream.
+func (c *Client) Write(ctx context.Context, key string, lines []*Line) error {
+	body, err := json.Marshal(lines)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, c.url("/stream", key), bytes.NewReader(body), nil)
+}
+
+// Upload uploads the full log history to the blob store.
+func (c *Client) Upload(ctx context.Context, key string, r io.Reader) error {
+	return c.do(ctx, http.MethodPost, c.url("/blob", key), r, nil)
+}
+
+// UploadLink returns a signed link used to upload the
+// full log history directly to the blob store.
+func (c *Client) UploadLink(ctx context.Context, key string) (*Link, error) {
+	out := new(Link)
+	err := c.do(ctx, http.MethodPost, c.url("/blob/link/upload", key), nil, out)
+	return out, err
+}
+
+// UploadUsingLink uploads the full log history using
+// the signed link.
+func (c *Client) UploadUsingLink(ctx context.Context, link string, r io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, link, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return c.send(req, nil)
+}
+
+func (c *Client) url(path, key string) string {
+	return fmt.Sprintf("%s%s?accountID=%s&key=%s",
+		c.endpoint,
+		path,
+		url.QueryEscape(c.account),
+		url.QueryEscape(key),
+	)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Harness-Token", c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+func (c *Client) send(req *http.Request, out interface{}) error {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("log service: %s %s: %d %s", req.Method, req.URL.Path, res.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out != nil {
+		return json.NewDecoder(res.Body).Decode(out)
+	}
+	return nil
+}
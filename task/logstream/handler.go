@@ -0,0 +1,54 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logstream
+
+import (
+	"context"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/masker"
+)
+
+// Handler returns a middleware that streams the task logs
+// to the log service when the task includes logging
+// instructions. The task logs are masked using the task
+// secrets and the configured masks.
+func Handler(next task.Handler) task.Handler {
+	return task.HandlerFunc(func(ctx context.Context, req *task.Request) task.Response {
+		conf := req.Task.Logger
+		if conf == nil || conf.Address == "" {
+			return next.Handle(ctx, req)
+		}
+
+		log := logger.FromContext(ctx).WithField("log.key", conf.Key)
+
+		account := conf.Account
+		if account == "" {
+			account = req.Account
+		}
+
+		client := New(conf.Address, account, conf.Token, conf.Insecure)
+		w, err := NewWriter(ctx, client, conf)
+		if err != nil {
+			log.WithError(err).Error("cannot open log stream")
+			return task.Error(err)
+		}
+		defer func() {
+			if err := w.Close(); err != nil {
+				log.WithError(err).Warn("cannot close log stream")
+			}
+		}()
+
+		req.Logger = masker.New(w, masks(conf, req))
+		return next.Handle(ctx, req)
+	})
+}
+
+// masks returns the list of values that must be masked
+// in the task logs.
+func masks(conf *task.Logger, req *task.Request) []string {
+	return append(masker.Slice(req.Secrets), conf.Masks...)
+}
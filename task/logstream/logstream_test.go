@@ -0,0 +1,232 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+)
+
+// server is a stand-in for the log service that records
+// the requests and the streamed lines.
+type server struct {
+	sync.Mutex
+	calls    []string
+	lines    []*Line
+	uploaded []*Line
+	url      string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.URL.Path == "/stream" && r.Method == http.MethodPut:
+		var lines []*Line
+		json.NewDecoder(r.Body).Decode(&lines)
+		s.lines = append(s.lines, lines...)
+	case r.URL.Path == "/blob/link/upload":
+		json.NewEncoder(w).Encode(&Link{Value: s.url + "/signed"})
+	case r.URL.Path == "/blob", r.URL.Path == "/signed":
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			line := new(Line)
+			json.Unmarshal(scanner.Bytes(), line)
+			s.uploaded = append(s.uploaded, line)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		conf  task.Logger
+		calls []string
+	}{
+		{
+			conf: task.Logger{Key: "key"},
+			calls: []string{
+				"POST /stream",
+				"PUT /stream",
+				"POST /blob",
+				"DELETE /stream",
+			},
+		},
+		{
+			conf: task.Logger{Key: "key", IndirectUpload: true, SkipOpeningStream: true, SkipClosingStream: true},
+			calls: []string{
+				"PUT /stream",
+				"POST /blob/link/upload",
+				"PUT /signed",
+			},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			s := new(server)
+			ts := httptest.NewServer(s)
+			defer ts.Close()
+			s.url = ts.URL
+
+			conf := test.conf
+			conf.Address = ts.URL
+			conf.Masks = []string{"hunter2"}
+
+			h := Handler(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+				io.WriteString(req.Logger, "hello world\n")
+				io.WriteString(req.Logger, "token mySecret\n")
+				io.WriteString(req.Logger, "password hunter2")
+				return task.Respond("ok")
+			}))
+
+			h.Handle(context.Background(), &task.Request{
+				Task:    &task.Task{Logger: &conf},
+				Secrets: []*common.Secret{{ID: "token", Value: "mySecret"}},
+			})
+
+			if got, want := strings.Join(s.calls, ","), strings.Join(test.calls, ","); got != want {
+				t.Errorf("Want calls %s, got %s", want, got)
+			}
+
+			want := []string{"hello world\n", "token [redacted]\n", "password [redacted]"}
+			if got := len(s.lines); got != len(want) {
+				t.Fatalf("Want %d lines, got %d", len(want), got)
+			}
+			for i, line := range s.lines {
+				if line.Message != want[i] {
+					t.Errorf("Want line %q, got %q", want[i], line.Message)
+				}
+				if line.Number != i {
+					t.Errorf("Want line number %d, got %d", i, line.Number)
+				}
+			}
+			if got := len(s.uploaded); got != len(want) {
+				t.Errorf("Want %d uploaded lines, got %d", len(want), got)
+			}
+		})
+	}
+}
+
+func TestWriter_TrimNewLineSuffix(t *testing.T) {
+	s := new(server)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	conf := &task.Logger{Key: "key", TrimNewLineSuffix: true}
+	w, err := NewWriter(context.Background(), New(ts.URL, "", "", false), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "hello\nworld\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.lines) != 2 || s.lines[0].Message != "hello" || s.lines[1].Message != "world" {
+		t.Errorf("Want new line suffix trimmed")
+	}
+}
+
+func TestHandler_NoLogger(t *testing.T) {
+	var visited bool
+	h := Handler(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+		visited = true
+		return nil
+	}))
+	h.Handle(context.Background(), &task.Request{Task: &task.Task{}})
+	if !visited {
+		t.Errorf("Expect next handler invoked")
+	}
+}
+
+func TestWriter_RemoveHistory(t *testing.T) {
+	s := new(server)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	w, err := NewWriter(context.Background(), New(ts.URL, "", "", false), &task.Logger{Key: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := w.history.Name()
+	io.WriteString(w, "hello\nworld\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.uploaded) != 2 || s.uploaded[1].Message != "world\n" {
+		t.Errorf("Want history uploaded from spool file")
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("Want spool file removed after close")
+	}
+}
+
+func TestWriter_CloseTwice(t *testing.T) {
+	s := new(server)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	w, err := NewWriter(context.Background(), New(ts.URL, "", "", false), &task.Logger{Key: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "hello\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Error(err)
+	}
+	if len(s.uploaded) != 1 {
+		t.Errorf("Want history uploaded once, got %d lines", len(s.uploaded))
+	}
+}
+
+func TestWriter_CloseTimeout(t *testing.T) {
+	defer func(d time.Duration) { timeout = d }(timeout)
+	timeout = 50 * time.Millisecond
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blob" {
+			io.Copy(io.Discard, r.Body)
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	conf := &task.Logger{Key: "key", SkipOpeningStream: true}
+	w, err := NewWriter(context.Background(), New(ts.URL, "", "", false), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "hello\n")
+
+	done := make(chan error, 1)
+	go func() { done <- w.Close() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Want deadline exceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Want close bounded by timeout")
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/go-task/task"
+)
+
+var (
+	// flushInterval defines how often buffered lines are
+	// written to the log stream.
+	flushInterval = time.Second
+
+	// batchSize defines the number of buffered lines that
+	// triggers an immediate write to the log stream.
+	batchSize = 100
+
+	// timeout defines how long the writer waits for the log
+	// service, bounding each flush and the final upload and
+	// close, so a stalled service cannot hang the task.
+	timeout = time.Minute
+)
+
+// Writer is an io.WriteCloser that splits the output into
+// lines and writes batches of lines to the log stream. The
+// full log history is spooled to a temporary file, which is
+// uploaded when the writer is closed.
+type Writer struct {
+	ctx    context.Context
+	client *Client
+	conf   *task.Logger
+
+	mu      sync.Mutex
+	num     int
+	partial []byte
+	pending []*Line
+	history *os.File
+	enc     *json.Encoder
+
+	ready chan struct{}
+	close chan struct{}
+	done  chan struct{}
+	err   error
+
+	once     sync.Once
+	closeErr error
+}
+
+// NewWriter opens the log stream, unless configured to skip
+// opening the stream, and returns a Writer that streams the
+// logs to the log service.
+func NewWriter(ctx context.Context, client *Client, conf *task.Logger) (*Writer, error) {
+	ctx = context.WithoutCancel(ctx)
+	history, err := os.CreateTemp("", "go-task-log-")
+	if err != nil {
+		return nil, err
+	}
+	if !conf.SkipOpeningStream {
+		if err := client.Open(ctx, conf.Key); err != nil {
+			removeFile(history)
+			return nil, err
+		}
+	}
+	w := &Writer{
+		ctx:     ctx,
+		client:  client,
+		conf:    conf,
+		history: history,
+		enc:     json.NewEncoder(history),
+		ready:   make(chan struct{}, 1),
+		close:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.start()
+	return w, nil
+}
+
+// Write splits p into lines and buffers the lines to be
+// written to the log stream.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i == -1 {
+			break
+		}
+		w.push(string(w.partial[:i+1]))
+		w.partial = w.partial[i+1:]
+	}
+	if len(w.pending) >= batchSize {
+		select {
+		case w.ready <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes any buffered lines, uploads the full log
+// history and closes the log stream, unless configured to
+// skip closing the stream. Subsequent calls return the
+// result of the first call.
+func (w *Writer) Close() error {
+	w.once.Do(func() {
+		w.closeErr = w.shutdown()
+	})
+	return w.closeErr
+}
+
+// shutdown stops the flush loop and writes the remaining
+// lines and the log history to the log service.
+func (w *Writer) shutdown() error {
+	close(w.close)
+	<-w.done
+
+	w.mu.Lock()
+	if len(w.partial) != 0 {
+		w.push(string(w.partial))
+		w.partial = nil
+	}
+	errs := []error{w.err}
+	w.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(w.ctx, timeout)
+	defer cancel()
+
+	errs = append(errs, w.flush(ctx))
+	errs = append(errs, w.upload(ctx))
+	if !w.conf.SkipClosingStream {
+		errs = append(errs, w.client.Close(ctx, w.conf.Key))
+	}
+	removeFile(w.history)
+	return errors.Join(errs...)
+}
+
+// push adds a line to the pending batch and history.
+// The caller must hold the lock.
+func (w *Writer) push(s string) {
+	if w.conf.TrimNewLineSuffix {
+		s = strings.TrimSuffix(s, "\n")
+	}
+	line := &Line{
+		Level:     "info",
+		Number:    w.num,
+		Message:   s,
+		Timestamp: time.Now(),
+	}
+	w.num++
+	w.pending = append(w.pending, line)
+	if err := w.enc.Encode(line); err != nil {
+		w.setErr(err)
+	}
+}
+
+// setErr records the first error. The caller must hold
+// the lock.
+func (w *Writer) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// start flushes the pending lines at a regular interval
+// or once the batch size is reached.
+func (w *Writer) start() {
+	defer close(w.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.close:
+			return
+		case <-ticker.C:
+		case <-w.ready:
+		}
+		ctx, cancel := context.WithTimeout(w.ctx, timeout)
+		err := w.flush(ctx)
+		cancel()
+		if err != nil {
+			w.mu.Lock()
+			w.setErr(err)
+			w.mu.Unlock()
+		}
+	}
+}
+
+// flush writes the pending lines to the log stream.
+func (w *Writer) flush(ctx context.Context) error {
+	w.mu.Lock()
+	lines := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+	return w.client.Write(ctx, w.conf.Key, lines)
+}
+
+// upload uploads the full log history, either directly or
+// using a signed link when indirect upload is enabled.
+func (w *Writer) upload(ctx context.Context) error {
+	if _, err := w.history.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if !w.conf.IndirectUpload {
+		return w.client.Upload(ctx, w.conf.Key, w.history)
+	}
+	link, err := w.client.UploadLink(ctx, w.conf.Key)
+	if err != nil {
+		return err
+	}
+	return w.client.UploadUsingLink(ctx, link.Value, w.history)
+}
+
+// removeFile closes and removes the file.
+func removeFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package metrics provides prometheus metrics for the task
+// router, downloader and cgi driver.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drone/go-task/task"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// task outcomes.
+const (
+	OutcomeSuccess  = "success"
+	OutcomeFailure  = "failure"
+	OutcomeTimeout  = "timeout"
+	OutcomeCanceled = "canceled"
+)
+
+// Registry is the registry of the task metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	tasksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_requests_total",
+			Help: "Total number of tasks handled.",
+		},
+		[]string{"type", "driver", "outcome"},
+	)
+
+	taskErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_errors_total",
+			Help: "Total number of tasks that returned an error.",
+		},
+		[]string{"type", "driver", "outcome"},
+	)
+
+	taskDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "task_duration_seconds",
+			Help:    "Task execution latency in seconds.",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+		},
+		[]string{"type", "driver", "outcome"},
+	)
+
+	downloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_downloads_total",
+			Help: "Total number of artifact downloads by cache hit or miss.",
+		},
+		[]string{"kind", "cache"},
+	)
+
+	downloadErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_download_errors_total",
+			Help: "Total number of failed artifact downloads.",
+		},
+		[]string{"kind"},
+	)
+
+	cgiExecTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_cgi_exec_total",
+			Help: "Total number of CGI executions by response status and exit code.",
+		},
+		[]string{"status", "exit_code"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		tasksTotal,
+		taskErrors,
+		taskDuration,
+		downloadsTotal,
+		downloadErrors,
+		cgiExecTotal,
+	)
+}
+
+// Handler returns a middleware that records the task count,
+// latency and errors by task type, driver and outcome.
+func Handler(next task.Handler) task.Handler {
+	return task.HandlerFunc(func(ctx context.Context, req *task.Request) task.Response {
+		start := time.Now()
+		res := next.Handle(ctx, req)
+
+		var err error
+		if res != nil {
+			err = res.Error()
+		}
+		outcome := Outcome(err)
+		labels := prometheus.Labels{
+			"type":    req.Task.Type,
+			"driver":  req.Task.Driver,
+			"outcome": outcome,
+		}
+		tasksTotal.With(labels).Inc()
+		taskDuration.With(labels).Observe(time.Since(start).Seconds())
+		if err != nil {
+			taskErrors.With(labels).Inc()
+		}
+		return res
+	})
+}
+
+// Outcome returns the task outcome for the error.
+func Outcome(err error) string {
+	var timeout *task.TimeoutError
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, &timeout):
+		return OutcomeTimeout
+	case errors.Is(err, task.ErrCanceled),
+		errors.Is(err, context.Canceled):
+		return OutcomeCanceled
+	default:
+		return OutcomeFailure
+	}
+}
+
+// Download records an artifact download of the kind,
+// for example repo or executable.
+func Download(kind string, hit bool) {
+	cache := "miss"
+	if hit {
+		cache = "hit"
+	}
+	downloadsTotal.WithLabelValues(kind, cache).Inc()
+}
+
+// DownloadError records a failed artifact download.
+func DownloadError(kind string) {
+	downloadErrors.WithLabelValues(kind).Inc()
+}
+
+// CGIExec records the response status and process exit
+// code of a CGI execution.
+func CGIExec(status, exitCode int) {
+	cgiExecTotal.WithLabelValues(strconv.Itoa(status), strconv.Itoa(exitCode)).Inc()
+}
+
+// Server returns an http.Handler that serves the metrics
+// in the prometheus exposition format.
+func Server() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drone/go-task/task"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHandler(t *testing.T) {
+	router := task.NewRouter()
+	router.Use(Handler)
+	router.RegisterFunc("ping", func(context.Context, *task.Request) task.Response {
+		return task.Respond("pong")
+	})
+	router.RegisterFunc("fail", func(context.Context, *task.Request) task.Response {
+		return task.Errorf("boom")
+	})
+
+	router.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "ping"}})
+	router.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "ping"}})
+	router.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "fail"}})
+
+	if got := testutil.ToFloat64(tasksTotal.WithLabelValues("ping", "", OutcomeSuccess)); got != 2 {
+		t.Errorf("Want 2 successful tasks, got %v", got)
+	}
+	if got := testutil.ToFloat64(taskErrors.WithLabelValues("fail", "", OutcomeFailure)); got != 1 {
+		t.Errorf("Want 1 task error, got %v", got)
+	}
+	if got := testutil.CollectAndCount(taskDuration); got != 2 {
+		t.Errorf("Want 2 latency histograms, got %v", got)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, OutcomeSuccess},
+		{errors.New("boom"), OutcomeFailure},
+		{task.ErrCanceled, OutcomeCanceled},
+		{&task.TimeoutError{Phase: task.PhaseExec}, OutcomeTimeout},
+	}
+	for _, test := range tests {
+		if got := Outcome(test.err); got != test.want {
+			t.Errorf("Want outcome %s for %v, got %s", test.want, test.err, got)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	Download("repo", true)
+	CGIExec(200, 0)
+
+	w := httptest.NewRecorder()
+	Server().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`task_downloads_total{cache="hit",kind="repo"} 1`,
+		`task_cgi_exec_total{exit_code="0",status="200"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Want metrics to include %s", want)
+		}
+	}
+}
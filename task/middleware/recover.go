@@ -0,0 +1,68 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/masker"
+)
+
+// PanicError is returned when the handler panics. The
+// panic value and stack trace are masked.
+type PanicError struct {
+	Value string
+	Stack string
+}
+
+// Error returns the panic value.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panic: %s", e.Value)
+}
+
+// Recover is a middleware that recovers from panics in the
+// handler, and returns an error response with the stack
+// trace. The panic value and stack trace are masked using
+// the request secrets, and logged.
+func Recover(next task.Handler) task.Handler {
+	return task.HandlerFunc(func(ctx context.Context, req *task.Request) (res task.Response) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			err := &PanicError{
+				Value: mask(fmt.Sprint(v), req.Secrets),
+				Stack: mask(string(debug.Stack()), req.Secrets),
+			}
+
+			logger.FromContext(ctx).
+				WithFields(map[string]interface{}{
+					"task.id":     req.Task.ID,
+					"task.type":   req.Task.Type,
+					"task.driver": req.Task.Driver,
+					"stack":       err.Stack,
+				}).
+				Error(err.Error())
+
+			res = task.Error(task.Fail(task.CodeExec, task.PhaseExec, "task panicked", err))
+		}()
+		return next.Handle(ctx, req)
+	})
+}
+
+// mask returns the string with the secrets masked.
+func mask(s string, secrets []*common.Secret) string {
+	var buf bytes.Buffer
+	io.WriteString(masker.New(&buf, masker.Slice(secrets)), s)
+	return buf.String()
+}
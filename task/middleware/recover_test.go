@@ -0,0 +1,52 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+)
+
+func TestRecover(t *testing.T) {
+	router := task.NewRouter()
+	router.Use(Recover)
+	router.RegisterFunc("panic", func(_ context.Context, req *task.Request) task.Response {
+		panic("cannot connect using password " + req.Secrets[0].Value)
+	})
+
+	res := router.Handle(context.Background(), &task.Request{
+		Task:    &task.Task{Type: "panic"},
+		Secrets: []*common.Secret{{ID: "password", Value: "correct-horse"}},
+	})
+
+	panicErr := new(PanicError)
+	if !errors.As(res.Error(), &panicErr) {
+		t.Fatalf("Want panic error, got %v", res.Error())
+	}
+	if got, want := panicErr.Value, "cannot connect using password [redacted]"; got != want {
+		t.Errorf("Want masked panic value %q, got %q", want, got)
+	}
+	if !strings.Contains(panicErr.Stack, "TestRecover") {
+		t.Errorf("Want stack trace of the panic")
+	}
+	if strings.Contains(res.Error().Error(), "correct-horse") {
+		t.Errorf("Expect secret masked in the error")
+	}
+}
+
+func TestRecover_NoPanic(t *testing.T) {
+	h := Recover(task.HandlerFunc(func(context.Context, *task.Request) task.Response {
+		return task.Respond("pong")
+	}))
+	res := h.Handle(context.Background(), &task.Request{Task: &task.Task{}})
+	if res.Error() != nil || string(res.Body()) != "pong" {
+		t.Errorf("Want response returned unchanged")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package middleware provides reusable task router middleware.
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/logger"
+)
+
+// Policy configures the retry behavior.
+type Policy struct {
+	// MaxAttempts provides the maximum number of attempts,
+	// including the first attempt.
+	MaxAttempts int
+
+	// Backoff provides the delay before the first retry.
+	Backoff time.Duration
+
+	// MaxBackoff provides the maximum delay between retries.
+	MaxBackoff time.Duration
+
+	// Multiplier provides the factor by which the delay
+	// increases after each retry.
+	Multiplier float64
+
+	// Jitter provides the fraction of the delay that is
+	// randomized, between 0 and 1.
+	Jitter float64
+
+	// Retryable reports whether the response is a transient
+	// failure that should be retried. Defaults to Retryable,
+	// which does not retry based on the CGI status code. Use
+	// RetryableStatus for idempotent task types only.
+	Retryable func(task.Response) bool
+}
+
+// DefaultPolicy provides the default retry policy.
+var DefaultPolicy = Policy{
+	MaxAttempts: 3,
+	Backoff:     500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+}
+
+// sleep waits for the duration or until the context is
+// cancelled, as a function for mocking.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Retry returns a middleware that retries transient task
+// failures. The policy registered for the task type is
+// used if found, else the default policy is used.
+func Retry(policy Policy, policies map[string]Policy) func(task.Handler) task.Handler {
+	return func(next task.Handler) task.Handler {
+		return task.HandlerFunc(func(ctx context.Context, req *task.Request) task.Response {
+			p, ok := policies[req.Task.Type]
+			if !ok {
+				p = policy
+			}
+			retryable := p.Retryable
+			if retryable == nil {
+				retryable = Retryable
+			}
+
+			log := logger.FromContext(ctx)
+			delay := p.Backoff
+
+			var res task.Response
+			for attempt := 1; ; attempt++ {
+				attemptLog := log.WithField("attempt", attempt)
+				attemptLog.Debug("task attempt")
+
+				res = next.Handle(logger.WithContext(ctx, attemptLog), req)
+				if attempt >= p.MaxAttempts || !retryable(res) {
+					return res
+				}
+
+				wait := jitter(delay, p.Jitter)
+				attemptLog.WithField("backoff", wait).Warn("task attempt failed, retrying")
+				if err := sleep(ctx, wait); err != nil {
+					return res
+				}
+				delay = backoff(delay, p.Multiplier, p.MaxBackoff)
+			}
+		})
+	}
+}
+
+// Retryable reports whether the response is a transient
+// failure. Transport errors and retryable task failures are
+// retryable. CGI responses are never retried based on the
+// status code, since the task may not be idempotent.
+func Retryable(res task.Response) bool {
+	if res == nil {
+		return false
+	}
+	err := res.Error()
+	if err == nil {
+		return false
+	}
+	if failure := new(task.Failure); errors.As(err, &failure) && failure.Retryable {
+		return true
+	}
+	return isTransportError(err)
+}
+
+// RetryableStatus reports whether the response is a
+// transient failure, including CGI responses with a 5xx or
+// 429 status code. It should only be registered for task
+// types that are safe to run more than once.
+func RetryableStatus(res task.Response) bool {
+	if res == nil {
+		return false
+	}
+	if res.Error() != nil {
+		return Retryable(res)
+	}
+	out := new(task.CGITaskResponse)
+	if err := json.Unmarshal(res.Body(), out); err != nil {
+		return false
+	}
+	return out.StatusCode >= 500 || out.StatusCode == http.StatusTooManyRequests
+}
+
+// isTransportError reports whether the error is a
+// transient network error. Only timeouts, refused or reset
+// connections and connections closed mid-response are
+// transient; certificate, DNS and malformed URL errors are
+// not retried.
+func isTransportError(err error) bool {
+	switch {
+	case errors.Is(err, task.ErrCanceled),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff returns the next delay.
+func backoff(d time.Duration, multiplier float64, max time.Duration) time.Duration {
+	if multiplier > 0 {
+		d = time.Duration(float64(d) * multiplier)
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// jitter randomizes the delay by the fraction.
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	delta := float64(d) * fraction
+	return time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task"
+)
+
+func TestRetry(t *testing.T) {
+	originalSleep := sleep
+	defer func() { sleep = originalSleep }() // Restore after the test
+
+	var delays []time.Duration
+	sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	var attempts int
+	router := task.NewRouter()
+	router.Use(Retry(DefaultPolicy, map[string]Policy{
+		"flaky": {MaxAttempts: 4, Backoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 2, Retryable: RetryableStatus},
+	}))
+	router.RegisterFunc("flaky", func(_ context.Context, req *task.Request) task.Response {
+		attempts++
+		if attempts < 4 {
+			return task.Respond(&task.CGITaskResponse{StatusCode: 503})
+		}
+		return task.Respond(&task.CGITaskResponse{StatusCode: 200})
+	})
+
+	res := router.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "flaky"}})
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := attempts, 4; got != want {
+		t.Errorf("Want %d attempts, got %d", want, got)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if len(delays) != len(want) {
+		t.Fatalf("Want delays %v, got %v", want, delays)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("Want delays %v, got %v", want, delays)
+		}
+	}
+}
+
+func TestRetry_MaxAttempts(t *testing.T) {
+	originalSleep := sleep
+	defer func() { sleep = originalSleep }() // Restore after the test
+	sleep = func(context.Context, time.Duration) error { return nil }
+
+	var attempts int
+	h := Retry(DefaultPolicy, nil)(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+		attempts++
+		return task.Error(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})
+	}))
+	res := h.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "ping"}})
+	if res.Error() == nil {
+		t.Errorf("Expect error after max attempts")
+	}
+	if got, want := attempts, DefaultPolicy.MaxAttempts; got != want {
+		t.Errorf("Want %d attempts, got %d", want, got)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		res  task.Response
+		want bool
+	}{
+		{task.Respond(&task.CGITaskResponse{StatusCode: 200}), false},
+		{task.Respond(&task.CGITaskResponse{StatusCode: 404}), false},
+		{task.Respond(&task.CGITaskResponse{StatusCode: 429}), false},
+		{task.Respond(&task.CGITaskResponse{StatusCode: 502}), false},
+		{task.Respond("pong"), false},
+		{task.Errorf("bad request"), false},
+		{task.Error(task.ErrCanceled), false},
+		{task.Error(&task.TimeoutError{Phase: task.PhaseExec}), false},
+		{task.Error(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), true},
+		{task.Error(&net.OpError{Op: "read", Err: syscall.ECONNRESET}), true},
+		{task.Error(&url.Error{Op: "Post", URL: "https://example.com", Err: io.ErrUnexpectedEOF}), true},
+		{task.Error(&url.Error{Op: "Post", URL: "https://example.com", Err: io.EOF}), false},
+		{task.Error(io.EOF), false},
+		{task.Error(&url.Error{Op: "Post", URL: "https://example.com", Err: timeoutError{}}), true},
+		{task.Error(&url.Error{Op: "Post", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}), false},
+		{task.Error(&url.Error{Op: "Post", URL: "https://example.com", Err: &tls.CertificateVerificationError{Err: errors.New("expired")}}), false},
+		{task.Error(&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), false},
+		{task.Error(&task.Failure{Code: task.CodeTaskFailed, Retryable: true}), true},
+		{task.Error(&task.Failure{Code: task.CodeBuild}), false},
+		{nil, false},
+	}
+	for i, test := range tests {
+		if got := Retryable(test.res); got != test.want {
+			t.Errorf("Test %d: want retryable %v, got %v", i, test.want, got)
+		}
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	tests := []struct {
+		res  task.Response
+		want bool
+	}{
+		{task.Respond(&task.CGITaskResponse{StatusCode: 200}), false},
+		{task.Respond(&task.CGITaskResponse{StatusCode: 404}), false},
+		{task.Respond(&task.CGITaskResponse{StatusCode: 429}), true},
+		{task.Respond(&task.CGITaskResponse{StatusCode: 502}), true},
+		{task.Respond("pong"), false},
+		{task.Errorf("bad request"), false},
+		{task.Error(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), true},
+		{nil, false},
+	}
+	for i, test := range tests {
+		if got := RetryableStatus(test.res); got != test.want {
+			t.Errorf("Test %d: want retryable %v, got %v", i, test.want, got)
+		}
+	}
+}
+
+func TestRetry_StatusNotRetried(t *testing.T) {
+	var attempts int
+	h := Retry(DefaultPolicy, nil)(task.HandlerFunc(func(_ context.Context, req *task.Request) task.Response {
+		attempts++
+		return task.Respond(&task.CGITaskResponse{StatusCode: 503})
+	}))
+	h.Handle(context.Background(), &task.Request{Task: &task.Task{Type: "charge"}})
+	if attempts != 1 {
+		t.Errorf("Want status code not retried by default, got %d attempts", attempts)
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second, 0.5)
+		if d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Errorf("Want jitter within bounds, got %s", d)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrCanceled is returned when the task execution is
+// cancelled by the user.
+var ErrCanceled = errors.New("task canceled")
+
+// registry tracks in-flight task requests by request
+// identifier and task identifier.
+type registry struct {
+	mu    sync.Mutex
+	calls map[string]map[*call]struct{}
+}
+
+// call represents an in-flight task request.
+type call struct {
+	cancel context.CancelCauseFunc
+	keys   []string
+}
+
+// add registers the in-flight task request using the
+// provided keys. Empty keys are ignored.
+func (r *registry) add(cancel context.CancelCauseFunc, keys ...string) *call {
+	c := &call{cancel: cancel}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = map[string]map[*call]struct{}{}
+	}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if r.calls[key] == nil {
+			r.calls[key] = map[*call]struct{}{}
+		}
+		r.calls[key][c] = struct{}{}
+		c.keys = append(c.keys, key)
+	}
+	return c
+}
+
+// remove unregisters the in-flight task request.
+func (r *registry) remove(c *call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range c.keys {
+		delete(r.calls[key], c)
+		if len(r.calls[key]) == 0 {
+			delete(r.calls, key)
+		}
+	}
+}
+
+// cancel cancels all in-flight task requests registered
+// with the key, and returns false if none are found.
+func (r *registry) cancel(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls, ok := r.calls[key]
+	for c := range calls {
+		c.cancel(ErrCanceled)
+	}
+	return ok
+}
@@ -23,6 +23,11 @@ type Request struct {
 	// that are available to the task execution.
 	Secrets []*common.Secret `json:"-"`
 
+	// Outputs provides the named outputs of the previous
+	// task executions, keyed by task id and output name,
+	// for example build.version.
+	Outputs map[string]string `json:"-"`
+
 	// Account provides the account identifier.
 	Account string `json:"account"`
 
@@ -59,10 +59,17 @@ func Errorf(format string, a ...any) Response {
 
 // Result provides task results.
 type Result struct {
-	Err     error
+	Err error
+
+	// Secrets provides the names and values of secrets
+	// created by the task.
 	Secrets map[string]string
+
+	// Outputs provides the names and values of the task
+	// outputs.
 	Outputs map[string]string
-	Data    []byte
+
+	Data []byte
 }
 
 // Body gets the response body.
@@ -74,3 +81,21 @@ func (r *Result) Body() []byte {
 func (r *Result) Error() error {
 	return r.Err
 }
+
+// Outputs returns the named outputs of the response, or
+// nil if the response does not provide outputs.
+func Outputs(res Response) map[string]string {
+	if r, ok := res.(*Result); ok {
+		return r.Outputs
+	}
+	return nil
+}
+
+// Secrets returns the named secrets created by the task,
+// or nil if the response does not provide secrets.
+func Secrets(res Response) map[string]string {
+	if r, ok := res.(*Result); ok {
+		return r.Secrets
+	}
+	return nil
+}
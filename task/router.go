@@ -6,28 +6,36 @@ package task
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"io"
+	"time"
 
 	"github.com/drone/go-task/task/common"
 	"github.com/drone/go-task/task/expression"
 	"github.com/drone/go-task/task/logger"
+	"github.com/drone/go-task/task/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Router routes task execution requests to the
 // appropriate handler.
 type Router struct {
 	middleware []func(Handler) Handler
-	handlers   map[string]Handler
+	routes     routes
+	drivers    map[string]Handler
 	notfound   Handler
+	inflight   registry
+	timeout    time.Duration
+	workers    int
 }
 
 func NewRouter() *Router {
 	return &Router{
-		handlers: map[string]Handler{},
+		drivers: map[string]Handler{},
 	}
 }
 
@@ -36,9 +44,12 @@ func (h *Router) Use(fn func(Handler) Handler) {
 	h.middleware = append(h.middleware, fn)
 }
 
-// Register registers the Handler to the router.
+// Register registers the Handler to the router. A name
+// ending with a wildcard, for example custom/vault/*, routes
+// all task types with the prefix. An exact match takes
+// precedence, followed by the longest matching prefix.
 func (h *Router) Register(name string, handler Handler) {
-	h.handlers[name] = handler
+	h.routes.add(&route{pattern: name, handler: handler})
 }
 
 // RegisterFunc registers the HandlerFunc to the router.
@@ -46,6 +57,31 @@ func (h *Router) RegisterFunc(name string, handler HandlerFunc) {
 	h.Register(name, HandlerFunc(handler))
 }
 
+// RegisterDriver registers the Handler as the execution
+// driver with the given name. The driver handles tasks with
+// a matching Task.Driver when no route matches the task type.
+// A driver that implements Decoder is used to decode the
+// output of secret sub-tasks.
+func (h *Router) RegisterDriver(name string, handler Handler) {
+	h.drivers[name] = handler
+}
+
+// RegisterDriverFunc registers the HandlerFunc as the
+// execution driver with the given name.
+func (h *Router) RegisterDriverFunc(name string, handler HandlerFunc) {
+	h.RegisterDriver(name, HandlerFunc(handler))
+}
+
+// Route creates a route group with the prefix, and invokes
+// fn to register the group routes and middleware.
+func (h *Router) Route(prefix string, fn func(*Group)) *Group {
+	g := &Group{router: h, prefix: prefix}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
 // NotFound adds a handler to response whenever a
 // route cannot be found.
 func (h *Router) NotFound(handler Handler) {
@@ -58,8 +94,77 @@ func (h *Router) NotFoundFunc(handler HandlerFunc) {
 	h.NotFound(HandlerFunc(handler))
 }
 
+// Timeout sets the default execution timeout for tasks
+// that do not declare a timeout.
+func (h *Router) Timeout(d time.Duration) {
+	h.timeout = d
+}
+
+// SecretWorkers sets the maximum number of secret sub-tasks
+// that are handled concurrently.
+func (h *Router) SecretWorkers(n int) {
+	h.workers = n
+}
+
+// Cancel cancels the in-flight task requests with the
+// matching request identifier or task identifier. It
+// returns false if no in-flight task request is found.
+func (h *Router) Cancel(id string) bool {
+	return h.inflight.cancel(id)
+}
+
 // Handle routes the task request to a handler.
 func (h *Router) Handle(ctx context.Context, req *Request) Response {
+	// register the in-flight request so that it can be
+	// cancelled by request or task identifier.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	call := h.inflight.add(cancel, req.ID, req.Task.ID)
+	defer h.inflight.remove(call)
+
+	// bound the execution pipeline by the task timeout,
+	// or the router default timeout.
+	timeout := h.timeout
+	if req.Task.Timeout > 0 {
+		timeout = seconds(req.Task.Timeout)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	res := h.route(ctx, req)
+
+	// return a distinct error if the task was cancelled
+	// by the user.
+	if errors.Is(context.Cause(ctx), ErrCanceled) {
+		return Error(ErrCanceled)
+	}
+
+	// return a timeout error if the task exceeded the
+	// deadline, unless the handler already reported the
+	// phase that ran over.
+	if res != nil && res.Error() != nil {
+		if errors.As(res.Error(), new(*TimeoutError)) {
+			return res
+		}
+		if err := CheckTimeout(ctx, PhaseExec); err != nil {
+			return Error(err)
+		}
+	}
+	return res
+}
+
+// route routes the task request to a handler after
+// resolving the secret sub-tasks.
+func (h *Router) route(ctx context.Context, req *Request) (res Response) {
+	ctx, span := tracing.Start(ctx, "task.route",
+		tracing.Task(req.Task.ID, req.Task.Type, req.Task.Driver)...)
+	defer func() {
+		tracing.End(span, responseError(res))
+	}()
+
 	log := logger.FromContext(ctx).
 		WithFields(map[string]interface{}{
 			"task.id":     req.Task.ID,
@@ -71,14 +176,26 @@ func (h *Router) Handle(ctx context.Context, req *Request) Response {
 	// handle each secret sub-task before handling
 	// the primary task
 	if len(req.Tasks) > 0 {
-		taskSecrets, err := h.ResolveSecrets(ctx, req.Tasks)
+		taskSecrets, taskOutputs, err := h.resolve(ctx, req.Tasks)
 		if err != nil {
-			return Error(err)
+			if err := CheckTimeout(ctx, PhaseResolve); err != nil {
+				return Error(err)
+			}
+			return Error(Fail(CodeSecret, PhaseResolve, "failed to resolve secrets", err))
 		}
 		// Appending resolved secrets to existing secrets
 		// This handles the scenario for Runner execute mode
 		// where Delegate handles resolving secrets for the task
 		req.Secrets = append(req.Secrets, taskSecrets...)
+
+		// make the sub-task outputs available to the
+		// task expressions.
+		if len(taskOutputs) > 0 && req.Outputs == nil {
+			req.Outputs = map[string]string{}
+		}
+		for k, v := range taskOutputs {
+			req.Outputs[k] = v
+		}
 	}
 
 	// add the structured logger to the context.
@@ -94,59 +211,101 @@ func (h *Router) Handle(ctx context.Context, req *Request) Response {
 	return h.handle(ctx, req)
 }
 
+// ResolveSecrets handles the secret sub-tasks and returns
+// the resolved secrets in the order of the sub-tasks.
+//
+// Sub-tasks that reference the secrets of earlier sub-tasks
+// are handled once the referenced secrets are resolved, and
+// independent sub-tasks are handled concurrently. The first
+// error cancels the remaining sub-tasks.
+//
+// Secrets created by a sub-task are returned after the
+// sub-task secret, with the id <task>.<name>.
 func (h *Router) ResolveSecrets(ctx context.Context, tasks []*Task) ([]*common.Secret, error) {
-	secrets := []*common.Secret{}
-
-	// handle each secret sub-task
-	for _, subtask := range tasks {
-		subreq := new(Request)
-		subreq.Task = subtask
-		subreq.Secrets = secrets
+	secrets, _, err := h.resolve(ctx, tasks)
+	return secrets, err
+}
 
-		// handle the subtask and get the results.
-		res := h.handle(ctx, subreq)
+// resolve handles the secret sub-tasks and returns the
+// resolved secrets, and the outputs of the sub-tasks keyed
+// by task id and output name.
+func (h *Router) resolve(ctx context.Context, tasks []*Task) ([]*common.Secret, map[string]string, error) {
+	workers := h.workers
+	if workers <= 0 {
+		workers = defaultSecretWorkers
+	}
+	ctx, span := tracing.Start(ctx, "task.resolve_secrets",
+		attribute.Int("task.secrets", len(tasks)))
+	results, err := resolveGraph(ctx, tasks, workers, h.tracedResolveSecret)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		// immediately exit if the system fails
-		// to execute the secret task.
-		if err := res.Error(); err != nil {
-			return nil, err
+	secrets := []*common.Secret{}
+	outputs := map[string]string{}
+	for _, result := range results {
+		secrets = append(secrets, result.secret)
+		secrets = append(secrets, result.secrets...)
+		for k, v := range result.outputs {
+			outputs[k] = v
 		}
+	}
+	return secrets, outputs, nil
+}
 
-		var secretOutputBytes []byte
-		if subtask.Driver != "cgi" && subtask.Type != "cgi" {
-			// This is not CGI task
-			secretOutputBytes = res.Body()
-		} else {
-			// This is CGI task
-			// Decode the response body into a temporary
-			// data structure.
-			out := new(CGITaskResponse)
-			if err := json.Unmarshal(res.Body(), out); err != nil {
-				return nil, err
-			}
+// tracedResolveSecret resolves the secret sub-task in a span.
+func (h *Router) tracedResolveSecret(ctx context.Context, subtask *Task, secrets []*common.Secret, outputs map[string]string) (*resolution, error) {
+	ctx, span := tracing.Start(ctx, "task.resolve_secret",
+		tracing.Task(subtask.ID, subtask.Type, subtask.Driver)...)
+	result, err := h.resolveSecret(ctx, subtask, secrets, outputs)
+	tracing.End(span, err)
+	return result, err
+}
 
-			if decodedBody, err := base64.StdEncoding.DecodeString(out.Body); err != nil {
-				return nil, fmt.Errorf("failed to decode plugin response: %s. %s", subtask.ID, err)
-			} else {
-				if out.StatusCode > 299 {
-					// Check whether it's a successful CGI call. Fail the task if it's not, as we can't proceed without the secret.
-					return nil, fmt.Errorf("failed to retrieve secret: %s. %s", subtask.ID, decodedBody)
-				} else {
-					secretOutputBytes = decodedBody
-				}
-			}
-		}
+// resolveSecret handles the secret sub-task and decodes
+// the secret from the response.
+func (h *Router) resolveSecret(ctx context.Context, subtask *Task, secrets []*common.Secret, outputs map[string]string) (*resolution, error) {
+	subreq := new(Request)
+	subreq.Task = subtask
+	subreq.Secrets = secrets
+	subreq.Outputs = outputs
+
+	// handle the subtask and get the results.
+	res := h.handle(ctx, subreq)
+
+	// immediately exit if the system fails
+	// to execute the secret task.
+	if err := res.Error(); err != nil {
+		return nil, err
+	}
 
-		secretOutput := new(common.Secret)
-		if err := json.Unmarshal(secretOutputBytes, secretOutput); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal secret: %s. %s", subtask.ID, err)
+	// decode the task output if the handler wraps the
+	// output in a driver-specific envelope.
+	secretOutputBytes := res.Body()
+	if _, handler := h.lookup(subtask); handler != nil {
+		if decoder, ok := handler.(Decoder); ok {
+			var err error
+			if secretOutputBytes, err = decoder.Decode(res); err != nil {
+				// Fail the task if the driver call is not successful,
+				// as we can't proceed without the secret.
+				return nil, Fail(CodeSecret, PhaseResolve, "failed to retrieve secret",
+					fmt.Errorf("failed to retrieve secret: %s. %w", subtask.ID, err))
+			}
 		}
-		secretOutput.ID = subtask.ID
+	}
 
-		// add the secret to request
-		secrets = append(secrets, secretOutput)
+	secretOutput := new(common.Secret)
+	if err := json.Unmarshal(secretOutputBytes, secretOutput); err != nil {
+		return nil, Fail(CodeSecret, PhaseResolve, "failed to unmarshal secret",
+			fmt.Errorf("failed to unmarshal secret: %s. %s", subtask.ID, err))
 	}
-	return secrets, nil
+	secretOutput.ID = subtask.ID
+	return &resolution{
+		secret:  secretOutput,
+		secrets: secretList(qualify(subtask.ID, Secrets(res))),
+		outputs: qualify(subtask.ID, Outputs(res)),
+	}, nil
 }
 
 func (h *Router) ResolveExpressions(ctx context.Context, secrets []*common.Secret, taskData []byte) ([]byte, []string, error) {
@@ -160,28 +319,20 @@ func (h *Router) ResolveExpressions(ctx context.Context, secrets []*common.Secre
 
 // handle routes the task request to a handler.
 func (h *Router) handle(ctx context.Context, req *Request) Response {
-	// extract the task type
-	name := req.Task.Type
-
 	// lookup the task handler
-	handler, ok := h.handlers[name]
-	if !ok {
+	handler, _ := h.lookup(req.Task)
+	if handler == nil {
 		// error if no route found
-		if h.notfound == nil {
-			return Errorf("handler not found")
-		}
-
-		// else use the not found handler
-		// to handle the task.
-		handler = h.notfound
+		return Error(Fail(CodeNotFound, PhaseRoute, "handler not found", nil))
 	}
 
 	// evaluate expressions
 	var err error
 	var additionalMasks []string
-	req.Task.Data, additionalMasks, err = h.ResolveExpressions(ctx, req.Secrets, req.Task.Data)
+	resolver := expression.New(req.Secrets).WithOutputs(req.Outputs)
+	req.Task.Data, additionalMasks, err = resolver.Resolve(req.Task.Data)
 	if err != nil {
-		return Error(err)
+		return Error(Fail(CodeInvalid, PhaseResolve, "failed to resolve expressions", err))
 	}
 
 	addDerivedSecrets(req, additionalMasks)
@@ -190,6 +341,28 @@ func (h *Router) handle(ctx context.Context, req *Request) Response {
 	return chain(h.middleware, handler).Handle(ctx, req)
 }
 
+// lookup returns the handler for the task. A route for the
+// task type takes precedence, followed by the driver for the
+// task driver, and finally the not found handler. The raw
+// handler is returned without the route group middleware.
+func (h *Router) lookup(t *Task) (endpoint, raw Handler) {
+	if rt, ok := h.routes.match(t.Type); ok {
+		return rt.endpoint(), rt.handler
+	}
+	if driver, ok := h.drivers[t.Driver]; ok && t.Driver != "" {
+		return driver, driver
+	}
+	return h.notfound, h.notfound
+}
+
+// responseError returns the response error, if any.
+func responseError(res Response) error {
+	if res == nil {
+		return nil
+	}
+	return res.Error()
+}
+
 func addDerivedSecrets(req *Request, additionalMasks []string) {
 	for i, maskValue := range additionalMasks {
 		derivedSecret := &common.Secret{
@@ -7,10 +7,13 @@ package task
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/drone/go-task/task/common"
 )
@@ -248,3 +251,267 @@ func TestResolveExpressions(t *testing.T) {
 		t.Errorf("Want resolved task data with expressions %v, got %v", want, got)
 	}
 }
+
+// testDriver is a driver that wraps the task output in
+// the CGITaskResponse envelope.
+type testDriver struct {
+	HandlerFunc
+}
+
+func (d *testDriver) Decode(res Response) ([]byte, error) {
+	return DecodeCGIResponse(res)
+}
+
+func TestResolveSecrets_Envelope(t *testing.T) {
+	router := NewRouter()
+	router.RegisterDriver("http", &testDriver{func(_ context.Context, req *Request) Response {
+		body, _ := json.Marshal(&common.Secret{Value: "mySecret"})
+		return Respond(&CGITaskResponse{
+			StatusCode: 200,
+			Body:       base64.StdEncoding.EncodeToString(body),
+		})
+	}})
+
+	got, err := router.ResolveSecrets(noContext, []*Task{{ID: "secret_task_id", Type: "custom/secret", Driver: "http"}})
+	if err != nil {
+		t.Errorf("error when resolving secrets: %s", err)
+	}
+
+	want := []*common.Secret{{ID: "secret_task_id", Value: "mySecret"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Want resolved secrets %v, got %v", want, got)
+	}
+}
+
+func TestResolveSecrets_EnvelopeErr(t *testing.T) {
+	router := NewRouter()
+	router.RegisterDriver("cgi", &testDriver{func(_ context.Context, req *Request) Response {
+		return Respond(&CGITaskResponse{
+			StatusCode: 403,
+			Body:       base64.StdEncoding.EncodeToString([]byte("forbidden")),
+		})
+	}})
+
+	_, err := router.ResolveSecrets(noContext, []*Task{{ID: "secret_task_id", Type: "custom/secret", Driver: "cgi"}})
+	if err == nil {
+		t.Fatalf("Expect error when the driver call fails")
+	}
+	if got, want := err.Error(), "failed to retrieve secret: secret_task_id. forbidden"; got != want {
+		t.Errorf("Want error %s, got %s", want, got)
+	}
+}
+
+func TestRouter_Driver(t *testing.T) {
+	router := NewRouter()
+	router.RegisterFunc("custom/ping", func(_ context.Context, req *Request) Response {
+		return Respond("route")
+	})
+	router.RegisterDriverFunc("cgi", func(_ context.Context, req *Request) Response {
+		return Respond("driver")
+	})
+	router.NotFoundFunc(func(_ context.Context, req *Request) Response {
+		return Respond("notfound")
+	})
+
+	tests := []struct {
+		task *Task
+		want string
+	}{
+		{&Task{Type: "custom/ping", Driver: "cgi"}, "route"},
+		{&Task{Type: "custom/pong", Driver: "cgi"}, "driver"},
+		{&Task{Type: "custom/pong", Driver: "exec"}, "notfound"},
+		{&Task{Type: "custom/pong"}, "notfound"},
+	}
+	for _, test := range tests {
+		res := router.Handle(noContext, &Request{Task: test.task})
+		if got := string(res.Body()); got != test.want {
+			t.Errorf("Want task %s/%s handled by %s, got %s", test.task.Type, test.task.Driver, test.want, got)
+		}
+	}
+}
+
+func TestRouter_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	router := NewRouter()
+	router.RegisterFunc("sleep", func(ctx context.Context, req *Request) Response {
+		close(started)
+		<-ctx.Done()
+		return Error(ctx.Err())
+	})
+
+	go func() {
+		<-started
+		if !router.Cancel("task_id") {
+			t.Errorf("Expect in-flight task cancelled")
+		}
+	}()
+
+	res := router.Handle(noContext, &Request{
+		ID:   "request_id",
+		Task: &Task{ID: "task_id", Type: "sleep"},
+	})
+	if !errors.Is(res.Error(), ErrCanceled) {
+		t.Errorf("Want cancelled error, got %v", res.Error())
+	}
+	if router.Cancel("request_id") {
+		t.Errorf("Expect completed task removed from registry")
+	}
+}
+
+func TestRouter_Timeout(t *testing.T) {
+	router := NewRouter()
+	router.Timeout(10 * time.Millisecond)
+	router.RegisterFunc("sleep", func(ctx context.Context, req *Request) Response {
+		<-ctx.Done()
+		return Error(ctx.Err())
+	})
+
+	res := router.Handle(noContext, &Request{Task: &Task{Type: "sleep"}})
+	timeoutErr := new(TimeoutError)
+	if !errors.As(res.Error(), &timeoutErr) {
+		t.Fatalf("Want timeout error, got %v", res.Error())
+	}
+	if got, want := timeoutErr.Phase, PhaseExec; got != want {
+		t.Errorf("Want timeout phase %s, got %s", want, got)
+	}
+
+	res = router.Handle(noContext, &Request{
+		Task:  &Task{Type: "sleep"},
+		Tasks: []*Task{{ID: "secret", Type: "sleep"}},
+	})
+	if !errors.As(res.Error(), &timeoutErr) {
+		t.Fatalf("Want timeout error, got %v", res.Error())
+	}
+	if got, want := timeoutErr.Phase, PhaseResolve; got != want {
+		t.Errorf("Want timeout phase %s, got %s", want, got)
+	}
+	if got, want := timeoutErr.Timeout, 10*time.Millisecond; got != want {
+		t.Errorf("Want timeout %s, got %s", want, got)
+	}
+}
+
+func TestRouter_Wildcard(t *testing.T) {
+	router := NewRouter()
+	for _, pattern := range []string{"custom/*", "custom/vault/*", "custom/vault/get", "*"} {
+		pattern := pattern
+		router.RegisterFunc(pattern, func(_ context.Context, req *Request) Response {
+			return Respond(pattern)
+		})
+	}
+
+	tests := []struct {
+		name, want string
+	}{
+		{"custom/vault/get", "custom/vault/get"},
+		{"custom/vault/list", "custom/vault/*"},
+		{"custom/vault/kv/list", "custom/vault/*"},
+		{"custom/user/find", "custom/*"},
+		{"sample/exec", "*"},
+	}
+	for _, test := range tests {
+		res := router.Handle(noContext, &Request{Task: &Task{Type: test.name}})
+		if got := string(res.Body()); got != test.want {
+			t.Errorf("Want task type %s routed to %s, got %s", test.name, test.want, got)
+		}
+	}
+}
+
+func TestRouter_Group(t *testing.T) {
+	var visited []string
+	mw := func(name string) func(Handler) Handler {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, req *Request) Response {
+				visited = append(visited, name)
+				return next.Handle(ctx, req)
+			})
+		}
+	}
+
+	router := NewRouter()
+	router.Use(mw("router"))
+	router.Route("custom", func(g *Group) {
+		g.Use(mw("custom"))
+		g.Route("vault", func(g *Group) {
+			g.Use(mw("vault"))
+			g.RegisterFunc("*", func(_ context.Context, req *Request) Response {
+				return Respond("vault")
+			})
+		})
+		g.RegisterFunc("user/find", func(_ context.Context, req *Request) Response {
+			return Respond("user")
+		})
+	})
+
+	res := router.Handle(noContext, &Request{Task: &Task{Type: "custom/vault/get"}})
+	if got, want := string(res.Body()), "vault"; got != want {
+		t.Errorf("Want response body %s, got %s", want, got)
+	}
+	if got, want := visited, []string{"router", "custom", "vault"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Want middleware %v, got %v", want, got)
+	}
+
+	visited = nil
+	res = router.Handle(noContext, &Request{Task: &Task{Type: "custom/user/find"}})
+	if got, want := string(res.Body()), "user"; got != want {
+		t.Errorf("Want response body %s, got %s", want, got)
+	}
+	if got, want := visited, []string{"router", "custom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Want middleware %v, got %v", want, got)
+	}
+}
+
+func TestRouter_Outputs(t *testing.T) {
+	router := NewRouter()
+	router.RegisterFunc("build", func(_ context.Context, req *Request) Response {
+		return &Result{
+			Data:    []byte(`{"value":"password"}`),
+			Outputs: map[string]string{"version": "1.2.3"},
+			Secrets: map[string]string{"token": "s3cr3t"},
+		}
+	})
+	router.RegisterFunc("deploy", func(_ context.Context, req *Request) Response {
+		return Respond(&common.Secret{Value: string(req.Task.Data)})
+	})
+	router.RegisterFunc("ping", func(_ context.Context, req *Request) Response {
+		return Respond(req.Task.Data)
+	})
+
+	req := &Request{
+		Task: &Task{
+			Type: "ping",
+			Data: []byte(`{"version":"${{outputs.build.version}}"}`),
+		},
+		Tasks: []*Task{
+			{ID: "build", Type: "build"},
+			{ID: "deploy", Type: "deploy", Data: []byte(`{"version":"${{outputs.build.version}}","token":"${{secrets.build.token}}"}`)},
+		},
+	}
+	res := router.Handle(noContext, req)
+	if err := res.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the outputs of a sub-task are available to the later
+	// sub-tasks and the primary task.
+	if got, want := string(res.Body()), `{"version":"1.2.3"}`; got != want {
+		t.Errorf("Want body %s, got %s", want, got)
+	}
+	if got, want := req.Outputs["build.version"], "1.2.3"; got != want {
+		t.Errorf("Want output %s, got %s", want, got)
+	}
+
+	// the secrets created by a sub-task are added to the
+	// request secrets so that they are masked.
+	var found bool
+	for _, secret := range req.Secrets {
+		if secret.ID == "build.token" && secret.Value == "s3cr3t" {
+			found = true
+		}
+		if secret.ID == "deploy" && secret.Value != `{"token":"s3cr3t","version":"1.2.3"}` {
+			t.Errorf("Want sub-task data resolved from outputs, got %s", secret.Value)
+		}
+	}
+	if !found {
+		t.Errorf("Want sub-task secret added to the request secrets")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/drone/go-task/task/common"
+)
+
+// defaultSecretWorkers provides the default number of secret
+// sub-tasks that are handled concurrently.
+const defaultSecretWorkers = 4
+
+// secretRef matches a reference to a secret or output in
+// the task data or configuration, for example
+// ${{secrets.token}} or ${{outputs.build.version}}.
+var secretRef = regexp.MustCompile(`\$\{\{\s*(?:secrets|outputs)\.([^\s}]+)\s*\}\}`)
+
+// resolution provides the secret resolved by a sub-task,
+// and the secrets and outputs created by the sub-task.
+type resolution struct {
+	secret  *common.Secret
+	secrets []*common.Secret
+	outputs map[string]string
+}
+
+// resolveFunc resolves the secret sub-task, given the
+// secrets and outputs of the sub-tasks it depends on.
+type resolveFunc func(context.Context, *Task, []*common.Secret, map[string]string) (*resolution, error)
+
+// dependencies returns the indexes of the earlier sub-tasks
+// referenced by each sub-task, including indirect references.
+// A reference to a named secret or output of a sub-task, for
+// example ${{outputs.build.version}}, references the sub-task.
+// Only the task data is scanned, since expressions in the
+// driver config are not resolved.
+func dependencies(tasks []*Task) [][]int {
+	index := map[string]int{}
+	deps := make([][]int, len(tasks))
+	for i, t := range tasks {
+		seen := map[int]bool{}
+		for _, match := range secretRef.FindAllSubmatch(t.Data, -1) {
+			ref := string(match[1])
+			j, ok := index[ref]
+			if !ok {
+				id, _, _ := strings.Cut(ref, ".")
+				j, ok = index[id]
+			}
+			if !ok || seen[j] {
+				continue
+			}
+			seen[j] = true
+			// include the dependencies of the dependency,
+			// which are always resolved earlier.
+			for _, k := range deps[j] {
+				seen[k] = true
+			}
+		}
+		for j := range seen {
+			deps[i] = append(deps[i], j)
+		}
+		sort.Ints(deps[i])
+		if t.ID != "" {
+			index[t.ID] = i
+		}
+	}
+	return deps
+}
+
+// resolveGraph resolves the secret sub-tasks concurrently,
+// respecting the dependencies between the sub-tasks, and
+// returns the results in the order of the sub-tasks.
+func resolveGraph(ctx context.Context, tasks []*Task, workers int, resolve resolveFunc) ([]*resolution, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	deps := dependencies(tasks)
+
+	// count the unresolved dependencies of each sub-task,
+	// and the sub-tasks that depend on each sub-task.
+	pending := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for i := range tasks {
+		pending[i] = len(deps[i])
+		for _, j := range deps[i] {
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		results  = make([]*resolution, len(tasks))
+		sem      = make(chan struct{}, workers)
+	)
+
+	var schedule func(i int)
+	schedule = func(i int) {
+		// collect the secrets and outputs of the dependencies,
+		// which are resolved before the sub-task is scheduled.
+		secrets := []*common.Secret{}
+		outputs := map[string]string{}
+		for _, j := range deps[i] {
+			secrets = append(secrets, results[j].secret)
+			secrets = append(secrets, results[j].secrets...)
+			for k, v := range results[j].outputs {
+				outputs[k] = v
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			result, err := resolve(ctx, tasks[i], secrets, outputs)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr != nil {
+				return
+			}
+			if err != nil {
+				firstErr = err
+				cancel()
+				return
+			}
+			results[i] = result
+			for _, j := range dependents[i] {
+				if pending[j]--; pending[j] == 0 {
+					schedule(j)
+				}
+			}
+		}()
+	}
+
+	mu.Lock()
+	for i := range tasks {
+		if pending[i] == 0 {
+			schedule(i)
+		}
+	}
+	mu.Unlock()
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// qualify returns the secrets or outputs created by the
+// task, keyed by the task id and name.
+func qualify(id string, in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := map[string]string{}
+	for name, value := range in {
+		out[id+"."+name] = value
+	}
+	return out
+}
+
+// secretList converts the named secrets to a slice of
+// secrets, sorted by name.
+func secretList(in map[string]string) []*common.Secret {
+	var out []*common.Secret
+	for id, value := range in {
+		out = append(out, &common.Secret{ID: id, Value: value})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
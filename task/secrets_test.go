@@ -0,0 +1,101 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/go-task/task/common"
+)
+
+func TestDependencies(t *testing.T) {
+	tasks := []*Task{
+		{ID: "a"},
+		{ID: "b", Data: []byte(`{"token":"${{secrets.a}}"}`)},
+		{ID: "c"},
+		{ID: "d", Data: []byte(`{"x":"${{ secrets.b }}","y":"${{secrets.e}}"}`)},
+		{ID: "e"},
+		// expressions in the config are not resolved, and do
+		// not reference the sub-task.
+		{ID: "f", Config: []byte(`{"token":"${{secrets.c}}"}`)},
+	}
+	got := dependencies(tasks)
+	want := [][]int{nil, {0}, nil, {0, 1}, nil, nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Want dependencies %v, got %v", want, got)
+	}
+}
+
+func TestResolveSecrets_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	router := NewRouter()
+	router.RegisterFunc("slow", func(_ context.Context, req *Request) Response {
+		// block until both independent sub-tasks are
+		// handled concurrently.
+		wg.Done()
+		wg.Wait()
+		return Respond(&common.Secret{Value: req.Task.ID + "-value"})
+	})
+	router.RegisterFunc("echo", func(_ context.Context, req *Request) Response {
+		return Respond(&common.Secret{Value: string(req.Task.Data)})
+	})
+
+	got, err := router.ResolveSecrets(noContext, []*Task{
+		{ID: "a", Type: "slow"},
+		{ID: "b", Type: "slow"},
+		{ID: "c", Type: "echo", Data: []byte(`{"token":"${{secrets.a}}"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*common.Secret{
+		{ID: "a", Value: "a-value"},
+		{ID: "b", Value: "b-value"},
+		{ID: "c", Value: `{"token":"a-value"}`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Want resolved secrets %v, got %v", want, got)
+	}
+}
+
+func TestResolveSecrets_FailFast(t *testing.T) {
+	router := NewRouter()
+	router.SecretWorkers(2)
+	router.RegisterFunc("fail", func(_ context.Context, req *Request) Response {
+		return Errorf("vault unavailable")
+	})
+	router.RegisterFunc("slow", func(ctx context.Context, req *Request) Response {
+		select {
+		case <-ctx.Done():
+			return Error(ctx.Err())
+		case <-time.After(10 * time.Second):
+			return Respond(&common.Secret{})
+		}
+	})
+	router.RegisterFunc("never", func(_ context.Context, req *Request) Response {
+		t.Errorf("Expect dependent sub-task not handled")
+		return nil
+	})
+
+	start := time.Now()
+	_, err := router.ResolveSecrets(noContext, []*Task{
+		{ID: "a", Type: "slow"},
+		{ID: "b", Type: "fail"},
+		{ID: "c", Type: "never", Data: []byte(`{"token":"${{secrets.b}}"}`)},
+	})
+	if err == nil || err.Error() != "vault unavailable" {
+		t.Errorf("Want first error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expect remaining sub-tasks cancelled")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Phase identifies a phase of the task execution pipeline.
+type Phase string
+
+const (
+	PhaseRoute    = Phase("route")
+	PhaseResolve  = Phase("resolve")
+	PhaseDownload = Phase("download")
+	PhaseBuild    = Phase("build")
+	PhaseExec     = Phase("exec")
+)
+
+// TimeoutError is returned when the task execution exceeds
+// the deadline. It reports the phase that ran over.
+type TimeoutError struct {
+	Phase   Phase
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Timeout == 0 {
+		return fmt.Sprintf("task timed out during %s", e.Phase)
+	}
+	return fmt.Sprintf("task timed out after %s during %s", e.Timeout, e.Phase)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// WithTimeout returns a copy of the parent context that
+// expires after the timeout. A TimeoutError is returned by
+// CheckTimeout once the context expires.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeoutCause(parent, timeout, &TimeoutError{Timeout: timeout})
+}
+
+// CheckTimeout returns a TimeoutError for the phase if the
+// context deadline is exceeded, else nil.
+func CheckTimeout(ctx context.Context, phase Phase) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	err := &TimeoutError{Phase: phase}
+	if cause := new(TimeoutError); errors.As(context.Cause(ctx), &cause) {
+		err.Timeout = cause.Timeout
+	}
+	return err
+}
+
+// seconds converts a timeout in seconds to a duration.
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
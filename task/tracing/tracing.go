@@ -0,0 +1,171 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tracing provides OpenTelemetry tracing for the
+// task router, downloader, builder and cgi driver.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation name of the tracer.
+const name = "github.com/drone/go-task"
+
+// span attribute keys.
+const (
+	TaskID   = attribute.Key("task.id")
+	TaskType = attribute.Key("task.type")
+	Driver   = attribute.Key("task.driver")
+	CacheHit = attribute.Key("cache.hit")
+
+	ErrorCode  = attribute.Key("error.code")
+	ErrorPhase = attribute.Key("error.phase")
+)
+
+// failure is implemented by errors that report a failure
+// code and phase, such as task.Failure. It is declared here
+// to avoid importing the task package.
+type failure interface {
+	error
+	Status() (code, phase string)
+}
+
+// propagator propagates the trace context to the cgi
+// process using the W3C traceparent format.
+var propagator = propagation.TraceContext{}
+
+// Start starts a span with the attributes. The span is a
+// no-op unless a tracer provider is registered with otel.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, and ends the span. Only
+// the failure code and phase are recorded, since the error
+// message may contain secrets.
+func End(span trace.Span, err error) {
+	if err != nil {
+		code, phase := "unknown", ""
+		if f := failure(nil); errors.As(err, &f) {
+			code, phase = f.Status()
+		}
+		span.SetAttributes(ErrorCode.String(code))
+		if phase != "" {
+			span.SetAttributes(ErrorPhase.String(phase))
+		}
+		span.SetStatus(codes.Error, code)
+	}
+	span.End()
+}
+
+// Task returns the span attributes of the task.
+func Task(id, typ, driver string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{TaskID.String(id), TaskType.String(typ)}
+	if driver != "" {
+		attrs = append(attrs, Driver.String(driver))
+	}
+	return attrs
+}
+
+// Inject injects the trace context into the http headers.
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Environ returns the trace context as environment
+// variables, for example TRACEPARENT.
+func Environ(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	var env []string
+	for _, key := range carrier.Keys() {
+		env = append(env, strings.ToUpper(key)+"="+carrier.Get(key))
+	}
+	return env
+}
+
+// Extract extracts the trace context from the environment
+// variables. It can be used by a cgi task to continue the
+// trace of the runner.
+func Extract(ctx context.Context, environ []string) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		switch key {
+		case "TRACEPARENT", "TRACESTATE":
+			carrier.Set(strings.ToLower(key), value)
+		}
+	}
+	return propagator.Extract(ctx, carrier)
+}
+
+// Setup registers a tracer provider that exports spans to
+// the OTLP endpoint, and returns a function that flushes
+// and shuts down the provider. The exporter is configured
+// using the standard OTEL_EXPORTER_OTLP_* environment
+// variables.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return register(exporter), nil
+}
+
+// SetupWriter registers a tracer provider that exports
+// spans to the writer as JSON, for debugging, and returns
+// a function that flushes and shuts down the provider.
+func SetupWriter(w io.Writer) (func(context.Context) error, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+	return register(exporter), nil
+}
+
+// Configured reports whether an OTLP endpoint is configured
+// in the environment.
+func Configured() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// register registers a tracer provider that exports spans
+// in batches to the exporter.
+func register(exporter sdktrace.SpanExporter) func(context.Context) error {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown
+}
+
+// SetupInMemory registers a tracer provider that exports
+// spans synchronously to memory, for use in tests.
+func SetupInMemory() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(
+		sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+	)
+	return exporter
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(name)
+}
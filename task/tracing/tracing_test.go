@@ -0,0 +1,121 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/drone/go-task/task"
+	"github.com/drone/go-task/task/common"
+	"github.com/drone/go-task/task/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRouter(t *testing.T) {
+	exporter := tracing.SetupInMemory()
+
+	router := task.NewRouter()
+	router.RegisterFunc("secret", func(context.Context, *task.Request) task.Response {
+		return task.Respond(&common.Secret{Value: "password"})
+	})
+	router.RegisterFunc("fail", func(context.Context, *task.Request) task.Response {
+		return task.Errorf("boom")
+	})
+	router.Handle(context.Background(), &task.Request{
+		Task:  &task.Task{ID: "1", Type: "fail"},
+		Tasks: []*task.Task{{ID: "2", Type: "secret"}},
+	})
+
+	spans := exporter.GetSpans()
+	if got, want := len(spans), 3; got != want {
+		t.Fatalf("Want %d spans, got %d", want, got)
+	}
+	route := find(t, spans, "task.route")
+	if route.Status.Code != codes.Error {
+		t.Errorf("Want route span error status")
+	}
+	if !hasAttr(route, string(tracing.TaskID), "1") || !hasAttr(route, string(tracing.TaskType), "fail") {
+		t.Errorf("Want route span tagged with the task id and type, got %v", route.Attributes)
+	}
+	secret := find(t, spans, "task.resolve_secret")
+	if !hasAttr(secret, string(tracing.TaskID), "2") {
+		t.Errorf("Want secret span tagged with the sub-task id, got %v", secret.Attributes)
+	}
+	resolve := find(t, spans, "task.resolve_secrets")
+	if secret.Parent.SpanID() != resolve.SpanContext.SpanID() ||
+		resolve.Parent.SpanID() != route.SpanContext.SpanID() {
+		t.Errorf("Want secret spans nested in the route span")
+	}
+}
+
+func TestEnd(t *testing.T) {
+	exporter := tracing.SetupInMemory()
+
+	_, span := tracing.Start(context.Background(), "plain")
+	tracing.End(span, errors.New("token=password"))
+	_, span = tracing.Start(context.Background(), "failure")
+	tracing.End(span, task.Fail(task.CodeExec, task.PhaseExec, "exec failed", errors.New("token=password")))
+
+	spans := exporter.GetSpans()
+	plain := find(t, spans, "plain")
+	if plain.Status.Code != codes.Error || plain.Status.Description != "unknown" {
+		t.Errorf("Want unknown error status, got %v", plain.Status)
+	}
+	failure := find(t, spans, "failure")
+	if failure.Status.Description != string(task.CodeExec) {
+		t.Errorf("Want failure code status, got %v", failure.Status)
+	}
+	if !hasAttr(failure, string(tracing.ErrorPhase), string(task.PhaseExec)) {
+		t.Errorf("Want failure phase attribute, got %v", failure.Attributes)
+	}
+	for _, span := range spans {
+		if strings.Contains(fmt.Sprint(span.Status, span.Attributes, span.Events), "password") {
+			t.Errorf("Want error message not exported, got %v", span)
+		}
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	tracing.SetupInMemory()
+
+	ctx, span := tracing.Start(context.Background(), "test")
+	defer span.End()
+
+	env := tracing.Environ(ctx)
+	if len(env) == 0 {
+		t.Fatalf("Want traceparent environment variable")
+	}
+	got := trace.SpanContextFromContext(tracing.Extract(context.Background(), env))
+	if got.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("Want trace id %s, got %s", span.SpanContext().TraceID(), got.TraceID())
+	}
+}
+
+func find(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("Want span %s", name)
+	return tracetest.SpanStub{}
+}
+
+func hasAttr(span tracetest.SpanStub, key, value string) bool {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key && attr.Value.Emit() == value {
+			return true
+		}
+	}
+	return false
+}
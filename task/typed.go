@@ -0,0 +1,231 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Validator validates the decoded task input.
+type Validator interface {
+	Validate() error
+}
+
+// DecodeError is returned when the task data cannot be
+// decoded into the handler input, or the input is invalid.
+type DecodeError struct {
+	// Field provides the path of the invalid field, for
+	// example spec.image, if known.
+	Field string
+
+	// Err provides the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *DecodeError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid task data: %s", e.Err)
+	}
+	return fmt.Sprintf("invalid task data: field %s: %s", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Typed returns a HandlerFunc that decodes the task data
+// into the input, calls fn, and encodes the output as json.
+// Unlike Respond, string and byte slice outputs are also
+// encoded as json.
+//
+// The task data is decoded strictly and unknown fields are
+// rejected. If the input implements Validator, the input is
+// validated before fn is called. Decode and validation
+// errors are returned as a DecodeError.
+func Typed[In, Out any](fn func(context.Context, *Request, In) (Out, error)) HandlerFunc {
+	return func(ctx context.Context, req *Request) Response {
+		in, err := decodeInput[In](req.Task.Data)
+		if err != nil {
+			return Error(err)
+		}
+		out, err := fn(ctx, req, in)
+		if err != nil {
+			return Error(err)
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			return Error(err)
+		}
+		return &Result{Data: b}
+	}
+}
+
+// decodeInput decodes and validates the task data.
+func decodeInput[In any](data []byte) (In, error) {
+	var in In
+
+	// allocate the input if it is a pointer, so that the
+	// handler does not receive a nil input.
+	if v := reflect.ValueOf(&in).Elem(); v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+
+	if len(bytes.TrimSpace(data)) != 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			return in, decodeError(err, reflect.TypeOf(in), data)
+		}
+		if dec.More() {
+			return in, &DecodeError{Err: errors.New("unexpected data after the input")}
+		}
+	}
+
+	// the Validate method may be declared with a value
+	// or pointer receiver.
+	validator, ok := any(in).(Validator)
+	if !ok {
+		validator, ok = any(&in).(Validator)
+	}
+	if ok {
+		if err := validator.Validate(); err != nil {
+			if decodeErr := new(DecodeError); errors.As(err, &decodeErr) {
+				return in, decodeErr
+			}
+			return in, &DecodeError{Err: err}
+		}
+	}
+	return in, nil
+}
+
+// decodeError returns a DecodeError for the json error,
+// including the path of the invalid field if known.
+func decodeError(err error, t reflect.Type, data []byte) *DecodeError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &DecodeError{
+			Field: typeErr.Field,
+			Err:   fmt.Errorf("cannot use %s as %s", typeErr.Value, typeErr.Type),
+		}
+	}
+	// the json package does not export a type for unknown
+	// field errors, so the field name is parsed from the
+	// error message, and the path is found in the data.
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		name = strings.Trim(name, `"`)
+		var v any
+		if json.Unmarshal(data, &v) == nil {
+			if path, ok := unknownField(t, v, name, ""); ok {
+				name = path
+			}
+		}
+		return &DecodeError{
+			Field: name,
+			Err:   errors.New("unknown field"),
+		}
+	}
+	return &DecodeError{Err: err}
+}
+
+// unknownField returns the path of the first field with the
+// name that is not a field of the type, in the format of
+// json.UnmarshalTypeError, for example spec.items.0.name.
+func unknownField(t reflect.Type, v any, name, path string) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var elem reflect.Type
+			switch t.Kind() {
+			case reflect.Struct:
+				field, ok := structField(t, key)
+				if !ok {
+					if key == name {
+						return joinPath(path, key), true
+					}
+					continue
+				}
+				elem = field.Type
+			case reflect.Map:
+				elem = t.Elem()
+			default:
+				return "", false
+			}
+			if found, ok := unknownField(elem, v[key], name, joinPath(path, key)); ok {
+				return found, true
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return "", false
+		}
+		for i, elem := range v {
+			if found, ok := unknownField(t.Elem(), elem, name, joinPath(path, strconv.Itoa(i))); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
+
+// structField returns the struct field that the json key
+// is decoded into, including fields of embedded structs.
+func structField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found, ok := structField(embedded, key); ok {
+					return found, true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// joinPath appends the key to the field path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
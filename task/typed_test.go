@@ -0,0 +1,120 @@
+// Copyright 2024 Harness Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type typedInput struct {
+	Name string `json:"name"`
+	Spec struct {
+		Replicas   int `json:"replicas"`
+		Containers []struct {
+			Image string `json:"image"`
+		} `json:"containers"`
+		Labels map[string]struct {
+			Value string `json:"value"`
+		} `json:"labels"`
+	} `json:"spec"`
+}
+
+func (in *typedInput) Validate() error {
+	if in.Name == "" {
+		return &DecodeError{Field: "name", Err: errors.New("required")}
+	}
+	return nil
+}
+
+type typedOutput struct {
+	Greeting string `json:"greeting"`
+}
+
+func TestTyped(t *testing.T) {
+	handler := Typed(func(_ context.Context, _ *Request, in *typedInput) (*typedOutput, error) {
+		return &typedOutput{Greeting: "hello " + in.Name}, nil
+	})
+
+	tests := []struct {
+		data  string
+		body  string
+		field string
+		err   bool
+	}{
+		{data: `{"name":"world"}`, body: `{"greeting":"hello world"}`},
+		{data: `{"name":"world","color":"blue"}`, field: "color", err: true},
+		{data: `{"name":"world","spec":{"replicas":"3"}}`, field: "spec.replicas", err: true},
+		{data: `{"name":"world","spec":{"bogus":true}}`, field: "spec.bogus", err: true},
+		{data: `{"name":"world","spec":{"containers":[{"image":"a"},{"bogus":"b"}]}}`, field: "spec.containers.1.bogus", err: true},
+		{data: `{"name":"world","spec":{"labels":{"app":{"bogus":"c"}}}}`, field: "spec.labels.app.bogus", err: true},
+		{data: `{"spec":{"replicas":3}}`, field: "name", err: true},
+		{data: ``, field: "name", err: true},
+		{data: `{"name":`, err: true},
+	}
+	for _, test := range tests {
+		res := handler.Handle(noContext, &Request{Task: &Task{Data: []byte(test.data)}})
+		if !test.err {
+			if err := res.Error(); err != nil {
+				t.Errorf("Want no error for %s, got %s", test.data, err)
+			}
+			if got := string(res.Body()); got != test.body {
+				t.Errorf("Want body %s, got %s", test.body, got)
+			}
+			continue
+		}
+		decodeErr := new(DecodeError)
+		if !errors.As(res.Error(), &decodeErr) {
+			t.Errorf("Want decode error for %s, got %v", test.data, res.Error())
+			continue
+		}
+		if decodeErr.Field != test.field {
+			t.Errorf("Want invalid field %q for %s, got %q", test.field, test.data, decodeErr.Field)
+		}
+	}
+}
+
+func TestTyped_Encode(t *testing.T) {
+	tests := []struct {
+		handler Handler
+		body    string
+	}{
+		{
+			handler: Typed(func(context.Context, *Request, struct{}) (string, error) {
+				return "hello", nil
+			}),
+			body: `"hello"`,
+		},
+		{
+			handler: Typed(func(context.Context, *Request, struct{}) ([]byte, error) {
+				return []byte("hello"), nil
+			}),
+			body: `"aGVsbG8="`,
+		},
+		{
+			handler: Typed(func(context.Context, *Request, struct{}) (*typedOutput, error) {
+				return nil, nil
+			}),
+			body: `null`,
+		},
+	}
+	for _, test := range tests {
+		res := test.handler.Handle(noContext, &Request{Task: &Task{Data: []byte(`{}`)}})
+		if got := string(res.Body()); got != test.body {
+			t.Errorf("Want json body %s, got %s", test.body, got)
+		}
+	}
+}
+
+func TestTyped_Error(t *testing.T) {
+	handler := Typed(func(context.Context, *Request, struct{}) (string, error) {
+		return "", errors.New("boom")
+	})
+	res := handler.Handle(noContext, &Request{Task: &Task{Data: []byte(`{}`)}})
+	if err := res.Error(); err == nil || err.Error() != "boom" {
+		t.Errorf("Want handler error, got %v", err)
+	}
+}
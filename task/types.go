@@ -4,6 +4,14 @@
 
 package task
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
 type Task struct {
 	// ID provides a unique task identifier.
 	ID string `json:"id"`
@@ -21,6 +29,11 @@ type Task struct {
 	// Config provides the execution driver configuration.
 	Config []byte `json:"config"`
 
+	// Timeout provides the task execution timeout in
+	// seconds. The timeout bounds the whole execution
+	// pipeline, including secret resolution.
+	Timeout int `json:"timeout,omitempty"`
+
 	// Forward provides instructions for forwarding
 	// the task to another runner node in the network.
 	Forward *Forward `json:"forward"`
@@ -32,7 +45,7 @@ type Task struct {
 // Forward provides instructions for forward a task
 // to another runner node in the network.
 type Forward struct {
-	Address  string `json:"string"`
+	Address  string `json:"address"`
 	Insecure bool   `json:"insecure"`
 	Certs    Certs  `json:"certs"`
 }
@@ -89,25 +102,79 @@ type Executable struct {
 	Url  string `json:"url"`
 }
 
+// State provides the task execution state.
+type State struct {
+	// ID provides a unique task identifier.
+	ID string `json:"id"`
+
+	// Status provides the task status.
+	Status Status `json:"status,omitempty"`
+
+	// Started provides the task start date.
+	Started int64 `json:"started,omitempty"`
+
+	// Finished provides the task end date.
+	Finished int64 `json:"finished,omitempty"`
+}
+
+// Status provides the task execution status.
+type Status string
+
+const (
+	StatusUnknown = Status("")
+	StatusPending = Status("pending")
+	StatusRunning = Status("running")
+	StatusSuccess = Status("success")
+	StatusFailure = Status("failure")
+)
+
+// Done returns true if the task execution is complete.
+func (s Status) Done() bool {
+	return s == StatusSuccess || s == StatusFailure
+}
+
 type CGITaskResponse struct {
 	StatusCode int                 `json:"status_code"`
 	Headers    map[string][]string `json:"headers"`
 	Body       string              `json:"body"` // base64 encoded
-}
 
-// type State struct {
-// 	// ID provides a unique task identifier.
-// 	ID string `json:"id"`
+	// Usage provides the resource usage of the process.
+	Usage *Usage `json:"usage,omitempty"`
+}
 
-// 	// Status provides the task status.
-// 	Status Status `json:"status,omitempty"`
+// Usage provides the resource usage of a task process.
+type Usage struct {
+	// PeakMemory provides the peak memory usage in bytes,
+	// if known.
+	PeakMemory int64 `json:"peak_memory,omitempty"`
 
-// 	// Started provides the task start date.
-// 	Started int64 `json:"started,omitempty"`
+	UserTime time.Duration `json:"user_time"`
+	SysTime  time.Duration `json:"sys_time"`
+}
 
-// 	// Finished provides the task end date.
-// 	Finished int64 `json:"finished,omitempty"`
-// }
+// DecodeCGIResponse decodes the task output from the
+// CGITaskResponse envelope. A Failure is returned if the
+// status code indicates a failure.
+func DecodeCGIResponse(res Response) ([]byte, error) {
+	out := new(CGITaskResponse)
+	if err := json.Unmarshal(res.Body(), out); err != nil {
+		return nil, err
+	}
+	body, err := base64.StdEncoding.DecodeString(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode plugin response: %w", err)
+	}
+	if out.StatusCode > 299 {
+		return nil, &Failure{
+			Code:      CodeTaskFailed,
+			Phase:     PhaseExec,
+			Retryable: out.StatusCode >= 500 || out.StatusCode == http.StatusTooManyRequests,
+			Message:   fmt.Sprintf("task failed with status %d", out.StatusCode),
+			Err:       fmt.Errorf("%s", body),
+		}
+	}
+	return body, nil
+}
 
 // // Config configures the execution driver.
 // type Config struct {
@@ -128,15 +195,6 @@ type CGITaskResponse struct {
 // 	Token    string `json:"token"`
 // }
 
-// type Status string
-
-// const (
-// 	StatusUnknown = Status("")
-// 	StatusSuccess = Status("success")
-// 	StatusFailure = Status("failure")
-// 	StatusRunning = Status("running")
-// )
-
 // type Driver string
 
 // const (